@@ -0,0 +1,185 @@
+package format
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/alex13th/telebot/v1/telegram"
+)
+
+// UTF16Len
+//
+// Length of the text in UTF-16 code units, the unit of the entity offsets and lengths
+func UTF16Len(text string) int {
+	n := 0
+	for _, r := range text {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// EntityBuilder
+//
+// Message text with the entities instead of a parse mode, nothing is escaped.
+// Offsets and lengths of the entities are counted in UTF-16 code units,
+// so emoji and other non-BMP characters are measured as Telegram does.
+type EntityBuilder struct {
+	sb       strings.Builder
+	length   int
+	entities []telegram.MessageEntity
+}
+
+func NewEntities() *EntityBuilder {
+	return &EntityBuilder{}
+}
+
+func (b *EntityBuilder) Text(text string) *EntityBuilder {
+	b.sb.WriteString(text)
+	b.length += UTF16Len(text)
+	return b
+}
+
+func (b *EntityBuilder) Bold(text string) *EntityBuilder {
+	return b.Entity(telegram.MessageEntity{Type: Bold}, text)
+}
+
+func (b *EntityBuilder) Italic(text string) *EntityBuilder {
+	return b.Entity(telegram.MessageEntity{Type: Italic}, text)
+}
+
+func (b *EntityBuilder) Underline(text string) *EntityBuilder {
+	return b.Entity(telegram.MessageEntity{Type: Underline}, text)
+}
+
+func (b *EntityBuilder) Strikethrough(text string) *EntityBuilder {
+	return b.Entity(telegram.MessageEntity{Type: Strikethrough}, text)
+}
+
+func (b *EntityBuilder) Spoiler(text string) *EntityBuilder {
+	return b.Entity(telegram.MessageEntity{Type: Spoiler}, text)
+}
+
+func (b *EntityBuilder) Code(text string) *EntityBuilder {
+	return b.Entity(telegram.MessageEntity{Type: Code}, text)
+}
+
+func (b *EntityBuilder) Pre(code string, language string) *EntityBuilder {
+	return b.Entity(telegram.MessageEntity{Type: Pre, Language: language}, code)
+}
+
+func (b *EntityBuilder) Link(text string, url string) *EntityBuilder {
+	return b.Entity(telegram.MessageEntity{Type: TextLink, Url: url}, text)
+}
+
+func (b *EntityBuilder) Mention(text string, userId int) *EntityBuilder {
+	return b.Entity(telegram.MessageEntity{Type: TextMention, User: &telegram.User{Id: userId}}, text)
+}
+
+func (b *EntityBuilder) Blockquote(text string) *EntityBuilder {
+	return b.Entity(telegram.MessageEntity{Type: Blockquote}, text)
+}
+
+// Entity
+//
+// Add the text with the entity, the offset and the length of the entity are set by the builder.
+// The entity of an empty text is omitted.
+func (b *EntityBuilder) Entity(e telegram.MessageEntity, text string) *EntityBuilder {
+	e.Offset = b.length
+	b.Text(text)
+	e.Length = b.length - e.Offset
+	if e.Length > 0 {
+		b.entities = append(b.entities, e)
+	}
+	return b
+}
+
+func (b *EntityBuilder) String() string {
+	return b.sb.String()
+}
+
+func (b *EntityBuilder) Entities() []telegram.MessageEntity {
+	return b.entities
+}
+
+// Build
+//
+// Text and the entities of the message
+func (b *EntityBuilder) Build() (string, []telegram.MessageEntity) {
+	return b.String(), b.Entities()
+}
+
+func (b *EntityBuilder) SendMessage(chatId interface{}) telegram.SendMessage {
+	return telegram.SendMessage{ChatId: chatId, Text: b.String(), Entities: b.Entities()}
+}
+
+func (b *EntityBuilder) EditMessageText(chatId interface{}, messageId int) telegram.EditMessageText {
+	return telegram.EditMessageText{ChatId: chatId, MessageId: messageId, Text: b.String(), Entities: b.Entities()}
+}
+
+// Render
+//
+// Text with the entities rendered in the mode, e.g. for quoting a received message
+// or logging. Nested entities are rendered inside the outer ones, an entity crossing
+// the end of the outer one is cut at the end.
+func Render(mode Mode, text string, entities []telegram.MessageEntity) string {
+	units := utf16.Encode([]rune(text))
+	sorted := make([]telegram.MessageEntity, len(entities))
+	copy(sorted, entities)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Offset != sorted[j].Offset {
+			return sorted[i].Offset < sorted[j].Offset
+		}
+		return sorted[i].Length > sorted[j].Length
+	})
+	var sb strings.Builder
+	render(&sb, mode, units, sorted, 0, len(units))
+	return sb.String()
+}
+
+// RenderMessage
+//
+// Text of the message or the caption of a media message rendered in the mode
+func RenderMessage(mode Mode, msg telegram.Message) string {
+	if msg.Text == "" && msg.Caption != "" {
+		return Render(mode, msg.Caption, msg.CaptionEntities)
+	}
+	return Render(mode, msg.Text, msg.Entities)
+}
+
+// render
+//
+// Render the units from start to end with the sorted entities starting in the range
+func render(sb *strings.Builder, mode Mode, units []uint16, entities []telegram.MessageEntity, start int, end int) {
+	pos := start
+	for i := 0; i < len(entities); {
+		e := entities[i]
+		i++
+		if e.Offset < pos || e.Offset >= end || e.Length <= 0 {
+			continue
+		}
+		eEnd := e.Offset + e.Length
+		if eEnd > end {
+			eEnd = end
+		}
+		// The entities starting inside the entity are nested into it
+		j := i
+		for j < len(entities) && entities[j].Offset < eEnd {
+			j++
+		}
+		sb.WriteString(Escape(mode, string(utf16.Decode(units[pos:e.Offset]))))
+		var content strings.Builder
+		if e.Type == Code || e.Type == Pre {
+			content.WriteString(escapeEntity(mode, e, string(utf16.Decode(units[e.Offset:eEnd]))))
+		} else {
+			render(&content, mode, units, entities[i:j], e.Offset, eEnd)
+		}
+		writeMarkup(sb, mode, markup(mode, e, content.String()))
+		if mode == MarkdownV2 && e.Type == Blockquote && eEnd < end && units[eEnd] != '\n' {
+			sb.WriteString("\n")
+		}
+		pos = eEnd
+		i = j
+	}
+	sb.WriteString(Escape(mode, string(utf16.Decode(units[pos:end]))))
+}
@@ -0,0 +1,179 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/alex13th/telebot/v1/telegram"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUTF16Len(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "", want: 0},
+		{text: "abc", want: 3},
+		{text: "привет", want: 6},
+		{text: "👍", want: 2},
+		{text: "a👨‍👩‍👧b", want: 10},
+		{text: "𝐀", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := UTF16Len(tt.text); got != tt.want {
+				t.Errorf("UTF16Len() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntityBuilder_Build(t *testing.T) {
+	text, entities := NewEntities().
+		Text("👍 Hi, ").Bold("мир 🌍").Text("! ").
+		Link("site", "https://example.com").Text(" ").
+		Mention("user", 123).Italic("").Text("\n").
+		Pre("x := 1", "go").
+		Build()
+	wantText := "👍 Hi, мир 🌍! site user\nx := 1"
+	if text != wantText {
+		t.Errorf("EntityBuilder.Build() text = %v, want %v", text, wantText)
+	}
+	want := []telegram.MessageEntity{
+		{Type: "bold", Offset: 7, Length: 6},
+		{Type: "text_link", Offset: 15, Length: 4, Url: "https://example.com"},
+		{Type: "text_mention", Offset: 20, Length: 4, User: &telegram.User{Id: 123}},
+		{Type: "pre", Offset: 25, Length: 6, Language: "go"},
+	}
+	if diff := cmp.Diff(want, entities); diff != "" {
+		t.Errorf("EntityBuilder.Build() entities mismatch (-want +got):\n%s", diff)
+	}
+
+	msg := NewEntities().Text("a").Code("b").SendMessage(1)
+	wantMsg := telegram.SendMessage{ChatId: 1, Text: "ab", Entities: []telegram.MessageEntity{{Type: "code", Offset: 1, Length: 1}}}
+	if diff := cmp.Diff(wantMsg, msg); diff != "" {
+		t.Errorf("EntityBuilder.SendMessage() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		entities []telegram.MessageEntity
+		html     string
+		markdown string
+	}{
+		{
+			name:     "Plain",
+			text:     "a < b. c",
+			html:     "a &lt; b. c",
+			markdown: "a < b\\. c",
+		},
+		{
+			name:     "Emoji",
+			text:     "👍 bold 🌍 end",
+			entities: []telegram.MessageEntity{{Type: "bold", Offset: 3, Length: 7}},
+			html:     "👍 <b>bold 🌍</b> end",
+			markdown: "👍 *bold 🌍* end",
+		},
+		{
+			name: "Nested",
+			text: "bold italic link",
+			entities: []telegram.MessageEntity{
+				{Type: "italic", Offset: 5, Length: 6},
+				{Type: "bold", Offset: 0, Length: 16},
+				{Type: "text_link", Offset: 12, Length: 4, Url: "https://example.com/(a)"},
+			},
+			html:     `<b>bold <i>italic</i> <a href="https://example.com/(a)">link</a></b>`,
+			markdown: "*bold _italic_ [link](https://example.com/(a\\))*",
+		},
+		{
+			name: "Code",
+			text: "run `x` a<b.",
+			entities: []telegram.MessageEntity{
+				{Type: "code", Offset: 4, Length: 8},
+				{Type: "bold", Offset: 5, Length: 1},
+			},
+			html:     "run <code>`x` a&lt;b.</code>",
+			markdown: "run `\\`x\\` a<b.`",
+		},
+		{
+			name: "Pre",
+			text: "x := 1",
+			entities: []telegram.MessageEntity{
+				{Type: "pre", Offset: 0, Length: 6, Language: "go"},
+			},
+			html:     `<pre><code class="language-go">x := 1</code></pre>`,
+			markdown: "```go\nx := 1\n```",
+		},
+		{
+			name: "Mention",
+			text: "@user and User",
+			entities: []telegram.MessageEntity{
+				{Type: "mention", Offset: 0, Length: 5},
+				{Type: "text_mention", Offset: 10, Length: 4, User: &telegram.User{Id: 123}},
+			},
+			html:     `@user and <a href="tg://user?id=123">User</a>`,
+			markdown: "@user and [User](tg://user?id=123)",
+		},
+		{
+			name: "Blockquote",
+			text: "line 1\nline 2 end",
+			entities: []telegram.MessageEntity{
+				{Type: "blockquote", Offset: 0, Length: 13},
+			},
+			html:     "<blockquote>line 1\nline 2</blockquote> end",
+			markdown: ">line 1\n>line 2\n end",
+		},
+		{
+			name: "Underscores",
+			text: "ab",
+			entities: []telegram.MessageEntity{
+				{Type: "underline", Offset: 0, Length: 2},
+				{Type: "italic", Offset: 1, Length: 1},
+			},
+			html:     "<u>a<i>b</i></u>",
+			markdown: "__a_b_**__",
+		},
+		{
+			name: "Crossing",
+			text: "abcdef",
+			entities: []telegram.MessageEntity{
+				{Type: "bold", Offset: 0, Length: 4},
+				{Type: "italic", Offset: 2, Length: 4},
+			},
+			html:     "<b>ab<i>cd</i></b>ef",
+			markdown: "*ab_cd_*ef",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(HTML, tt.text, tt.entities); got != tt.html {
+				t.Errorf("Render(HTML) = %q, want %q", got, tt.html)
+			}
+			if got := Render(MarkdownV2, tt.text, tt.entities); got != tt.markdown {
+				t.Errorf("Render(MarkdownV2) = %q, want %q", got, tt.markdown)
+			}
+		})
+	}
+}
+
+func TestRender_Builder(t *testing.T) {
+	b := NewEntities().Text("Hi ").Bold("👨‍👩‍👧 family").Text(" & ").Spoiler("secret.")
+	if got, want := Render(HTML, b.String(), b.Entities()),
+		NewHTML().Text("Hi ").Bold("👨‍👩‍👧 family").Text(" & ").Spoiler("secret.").String(); got != want {
+		t.Errorf("Render(HTML) = %q, want %q", got, want)
+	}
+	if got, want := Render(MarkdownV2, b.String(), b.Entities()),
+		NewMarkdownV2().Text("Hi ").Bold("👨‍👩‍👧 family").Text(" & ").Spoiler("secret.").String(); got != want {
+		t.Errorf("Render(MarkdownV2) = %q, want %q", got, want)
+	}
+}
+
+func TestRenderMessage(t *testing.T) {
+	msg := telegram.Message{Caption: "photo", CaptionEntities: []telegram.MessageEntity{{Type: "italic", Offset: 0, Length: 5}}}
+	if got := RenderMessage(HTML, msg); got != "<i>photo</i>" {
+		t.Errorf("RenderMessage() = %q, want %q", got, "<i>photo</i>")
+	}
+}
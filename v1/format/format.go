@@ -0,0 +1,254 @@
+// Package format builds message texts with escaped HTML or MarkdownV2 formatting
+// or with entities and renders the received entities back to the markup
+package format
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alex13th/telebot/v1/telegram"
+)
+
+// Mode
+//
+// Parse mode of the message text
+type Mode string
+
+const (
+	HTML       Mode = "HTML"
+	MarkdownV2 Mode = "MarkdownV2"
+)
+
+// Types of the message entities
+const (
+	Mention       = "mention"
+	Hashtag       = "hashtag"
+	Cashtag       = "cashtag"
+	BotCommand    = "bot_command"
+	URL           = "url"
+	Email         = "email"
+	PhoneNumber   = "phone_number"
+	Bold          = "bold"
+	Italic        = "italic"
+	Underline     = "underline"
+	Strikethrough = "strikethrough"
+	Spoiler       = "spoiler"
+	Blockquote    = "blockquote"
+	Code          = "code"
+	Pre           = "pre"
+	TextLink      = "text_link"
+	TextMention   = "text_mention"
+)
+
+var (
+	htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+	// Characters reserved by MarkdownV2 outside of code and links
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`,
+		"`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`,
+		"{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`)
+	markdownCodeEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+	markdownLinkEscaper = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+)
+
+// Escape
+//
+// Text escaped for the mode
+func Escape(mode Mode, text string) string {
+	if mode == MarkdownV2 {
+		return markdownEscaper.Replace(text)
+	}
+	return htmlEscaper.Replace(text)
+}
+
+// Builder
+//
+// Message text in the Mode. Every text passed to the builder is escaped,
+// so user input can't break the formatting.
+type Builder struct {
+	Mode Mode
+	sb   strings.Builder
+}
+
+func NewHTML() *Builder {
+	return &Builder{Mode: HTML}
+}
+
+func NewMarkdownV2() *Builder {
+	return &Builder{Mode: MarkdownV2}
+}
+
+func (b *Builder) Text(text string) *Builder {
+	b.sb.WriteString(Escape(b.Mode, text))
+	return b
+}
+
+func (b *Builder) Bold(text string) *Builder {
+	return b.entity(telegram.MessageEntity{Type: Bold}, text)
+}
+
+func (b *Builder) Italic(text string) *Builder {
+	return b.entity(telegram.MessageEntity{Type: Italic}, text)
+}
+
+func (b *Builder) Underline(text string) *Builder {
+	return b.entity(telegram.MessageEntity{Type: Underline}, text)
+}
+
+func (b *Builder) Strikethrough(text string) *Builder {
+	return b.entity(telegram.MessageEntity{Type: Strikethrough}, text)
+}
+
+func (b *Builder) Spoiler(text string) *Builder {
+	return b.entity(telegram.MessageEntity{Type: Spoiler}, text)
+}
+
+func (b *Builder) Code(text string) *Builder {
+	return b.entity(telegram.MessageEntity{Type: Code}, text)
+}
+
+// Pre
+//
+// Preformatted block of the code in the language, the language may be empty
+func (b *Builder) Pre(code string, language string) *Builder {
+	return b.entity(telegram.MessageEntity{Type: Pre, Language: language}, code)
+}
+
+func (b *Builder) Link(text string, url string) *Builder {
+	return b.entity(telegram.MessageEntity{Type: TextLink, Url: url}, text)
+}
+
+// Mention
+//
+// Link to the user by the id, it works for users without username
+func (b *Builder) Mention(text string, userId int) *Builder {
+	return b.entity(telegram.MessageEntity{Type: TextMention, User: &telegram.User{Id: userId}}, text)
+}
+
+// Blockquote
+//
+// Quotation of the text on its own lines, every line is quoted in MarkdownV2.
+// The quote starts on a new line and is followed by a line break in both modes.
+func (b *Builder) Blockquote(text string) *Builder {
+	if b.sb.Len() > 0 && !strings.HasSuffix(b.sb.String(), "\n") {
+		b.sb.WriteString("\n")
+	}
+	b.entity(telegram.MessageEntity{Type: Blockquote}, text)
+	b.sb.WriteString("\n")
+	return b
+}
+
+func (b *Builder) String() string {
+	return b.sb.String()
+}
+
+func (b *Builder) ParseMode() string {
+	return string(b.Mode)
+}
+
+// SendMessage
+//
+// Message with the text and the parse mode to the chat
+func (b *Builder) SendMessage(chatId interface{}) telegram.SendMessage {
+	return telegram.SendMessage{ChatId: chatId, Text: b.String(), ParseMode: b.ParseMode()}
+}
+
+// EditMessageText
+//
+// Edit the message text with the text and the parse mode
+func (b *Builder) EditMessageText(chatId interface{}, messageId int) telegram.EditMessageText {
+	return telegram.EditMessageText{ChatId: chatId, MessageId: messageId, Text: b.String(), ParseMode: b.ParseMode()}
+}
+
+func (b *Builder) entity(e telegram.MessageEntity, text string) *Builder {
+	writeMarkup(&b.sb, b.Mode, markup(b.Mode, e, escapeEntity(b.Mode, e, text)))
+	return b
+}
+
+// writeMarkup
+//
+// Write the markup, adjacent MarkdownV2 underscores are separated by an empty bold entity,
+// otherwise ___ is read as the underline marker first
+func writeMarkup(sb *strings.Builder, mode Mode, s string) {
+	if mode == MarkdownV2 && strings.HasPrefix(s, "_") && endsWithUnderscore(sb.String()) {
+		sb.WriteString("**")
+	}
+	sb.WriteString(s)
+}
+
+// escapeEntity
+//
+// Text escaped for the content of the entity, code is escaped less in MarkdownV2
+func escapeEntity(mode Mode, e telegram.MessageEntity, text string) string {
+	if mode == MarkdownV2 && (e.Type == Code || e.Type == Pre) {
+		return markdownCodeEscaper.Replace(text)
+	}
+	return Escape(mode, text)
+}
+
+// markup
+//
+// Entity markup around the escaped content, the entities without markup
+// like mentions and hashtags return the content as is
+func markup(mode Mode, e telegram.MessageEntity, content string) string {
+	if mode == MarkdownV2 {
+		return markdownMarkup(e, content)
+	}
+	switch e.Type {
+	case Bold, Italic, Underline, Strikethrough, Code, Blockquote:
+		tag := map[string]string{Bold: "b", Italic: "i", Underline: "u", Strikethrough: "s", Code: "code", Blockquote: "blockquote"}[e.Type]
+		return "<" + tag + ">" + content + "</" + tag + ">"
+	case Spoiler:
+		return "<tg-spoiler>" + content + "</tg-spoiler>"
+	case Pre:
+		if e.Language == "" {
+			return "<pre>" + content + "</pre>"
+		}
+		return `<pre><code class="language-` + htmlEscaper.Replace(e.Language) + `">` + content + "</code></pre>"
+	case TextLink:
+		return `<a href="` + htmlEscaper.Replace(e.Url) + `">` + content + "</a>"
+	case TextMention:
+		if e.User != nil {
+			return `<a href="tg://user?id=` + strconv.Itoa(e.User.Id) + `">` + content + "</a>"
+		}
+	}
+	return content
+}
+
+// endsWithUnderscore
+//
+// The MarkdownV2 markup ends with an unescaped underscore
+func endsWithUnderscore(s string) bool {
+	if !strings.HasSuffix(s, "_") {
+		return false
+	}
+	escapes := len(s) - 1 - len(strings.TrimRight(s[:len(s)-1], `\`))
+	return escapes%2 == 0
+}
+
+func markdownMarkup(e telegram.MessageEntity, content string) string {
+	switch e.Type {
+	case Bold, Italic, Underline, Strikethrough, Spoiler, Code:
+		marker := map[string]string{Bold: "*", Italic: "_", Underline: "__", Strikethrough: "~", Spoiler: "||", Code: "`"}[e.Type]
+		if strings.HasPrefix(marker, "_") {
+			if strings.HasPrefix(content, "_") {
+				content = "**" + content
+			}
+			if endsWithUnderscore(content) {
+				content += "**"
+			}
+		}
+		return marker + content + marker
+	case Pre:
+		return "```" + markdownCodeEscaper.Replace(e.Language) + "\n" + content + "\n```"
+	case TextLink:
+		return "[" + content + "](" + markdownLinkEscaper.Replace(e.Url) + ")"
+	case TextMention:
+		if e.User != nil {
+			return "[" + content + "](tg://user?id=" + strconv.Itoa(e.User.Id) + ")"
+		}
+	case Blockquote:
+		return ">" + strings.ReplaceAll(content, "\n", "\n>")
+	}
+	return content
+}
@@ -0,0 +1,136 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/alex13th/telebot/v1/telegram"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuilder_HTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *Builder)
+		want  string
+	}{
+		{
+			name:  "Text",
+			build: func(b *Builder) { b.Text(`a < b & "c" > d_*`) },
+			want:  "a &lt; b &amp; &quot;c&quot; &gt; d_*",
+		},
+		{
+			name: "Styles",
+			build: func(b *Builder) {
+				b.Bold("b").Italic("i").Underline("u").Strikethrough("s").Spoiler("<x>")
+			},
+			want: "<b>b</b><i>i</i><u>u</u><s>s</s><tg-spoiler>&lt;x&gt;</tg-spoiler>",
+		},
+		{
+			name:  "Code",
+			build: func(b *Builder) { b.Code("a<b").Pre("if a < b {}", "").Pre("x := 1", "go") },
+			want:  `<code>a&lt;b</code><pre>if a &lt; b {}</pre><pre><code class="language-go">x := 1</code></pre>`,
+		},
+		{
+			name:  "Link",
+			build: func(b *Builder) { b.Link("Site & co", `https://example.com/?a=1&b="2"`).Mention("User", 123) },
+			want:  `<a href="https://example.com/?a=1&amp;b=&quot;2&quot;">Site &amp; co</a><a href="tg://user?id=123">User</a>`,
+		},
+		{
+			name:  "Blockquote",
+			build: func(b *Builder) { b.Blockquote("line 1\nline <2>") },
+			want:  "<blockquote>line 1\nline &lt;2&gt;</blockquote>\n",
+		},
+		{
+			name:  "Blockquote after text",
+			build: func(b *Builder) { b.Text("Re: ").Blockquote("quoted").Text("end") },
+			want:  "Re: \n<blockquote>quoted</blockquote>\nend",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewHTML()
+			tt.build(b)
+			if got := b.String(); got != tt.want {
+				t.Errorf("Builder.String() = %v, want %v", got, tt.want)
+			}
+			if got := b.ParseMode(); got != "HTML" {
+				t.Errorf("Builder.ParseMode() = %v, want HTML", got)
+			}
+		})
+	}
+}
+
+func TestBuilder_MarkdownV2(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *Builder)
+		want  string
+	}{
+		{
+			name:  "Text",
+			build: func(b *Builder) { b.Text("1.5 + 2 = 3.5! (a_b) [c] *d* `e` ~f~ #g |h| {i} >j <k & \\") },
+			want:  "1\\.5 \\+ 2 \\= 3\\.5\\! \\(a\\_b\\) \\[c\\] \\*d\\* \\`e\\` \\~f\\~ \\#g \\|h\\| \\{i\\} \\>j <k & \\\\",
+		},
+		{
+			name: "Styles",
+			build: func(b *Builder) {
+				b.Bold("b.").Text(" ").Italic("i-").Text(" ").Underline("u_").Text(" ").Strikethrough("s~").Text(" ").Spoiler("x|")
+			},
+			want: "*b\\.* _i\\-_ __u\\___ ~s\\~~ ||x\\|||",
+		},
+		{
+			name:  "Underscores",
+			build: func(b *Builder) { b.Italic("i").Underline("u").Italic("i") },
+			want:  "_i_**__u__**_i_",
+		},
+		{
+			name:  "Code",
+			build: func(b *Builder) { b.Code("a.b `c` \\").Pre("x := `1`.", "go") },
+			want:  "`a.b \\`c\\` \\\\````go\nx := \\`1\\`.\n```",
+		},
+		{
+			name:  "Link",
+			build: func(b *Builder) { b.Link("Site (1)", "https://example.com/a_(b)").Mention("U.", 123) },
+			want:  "[Site \\(1\\)](https://example.com/a_(b\\))[U\\.](tg://user?id=123)",
+		},
+		{
+			name:  "Blockquote",
+			build: func(b *Builder) { b.Blockquote("line 1.\nline 2").Text("end") },
+			want:  ">line 1\\.\n>line 2\nend",
+		},
+		{
+			name:  "Blockquote after text",
+			build: func(b *Builder) { b.Text("Re: ").Blockquote("quoted") },
+			want:  "Re: \n>quoted\n",
+		},
+		{
+			name:  "Blockquote after line",
+			build: func(b *Builder) { b.Text("Re:\n").Blockquote("quoted") },
+			want:  "Re:\n>quoted\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewMarkdownV2()
+			tt.build(b)
+			if got := b.String(); got != tt.want {
+				t.Errorf("Builder.String() = %q, want %q", got, tt.want)
+			}
+			if got := b.ParseMode(); got != "MarkdownV2" {
+				t.Errorf("Builder.ParseMode() = %v, want MarkdownV2", got)
+			}
+		})
+	}
+}
+
+func TestBuilder_SendMessage(t *testing.T) {
+	b := NewMarkdownV2().Bold("Hi").Text("!")
+	want := telegram.SendMessage{ChatId: 1, Text: "*Hi*\\!", ParseMode: "MarkdownV2"}
+	if diff := cmp.Diff(want, b.SendMessage(1)); diff != "" {
+		t.Errorf("Builder.SendMessage() mismatch (-want +got):\n%s", diff)
+	}
+	edit := telegram.EditMessageText{ChatId: 1, MessageId: 2, Text: "*Hi*\\!", ParseMode: "MarkdownV2"}
+	if diff := cmp.Diff(edit, b.EditMessageText(1, 2)); diff != "" {
+		t.Errorf("Builder.EditMessageText() mismatch (-want +got):\n%s", diff)
+	}
+}
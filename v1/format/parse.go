@@ -0,0 +1,358 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alex13th/telebot/v1/telegram"
+)
+
+var ErrInvalidMarkup = errors.New("the text markup is invalid")
+
+var htmlAttrRegexp = regexp.MustCompile(`([a-zA-Z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+const mentionURL = "tg://user?id="
+
+// Parse
+//
+// Text and entities of the text in the parse mode, the text without a mode is returned as is
+func Parse(mode Mode, markup string) (string, []telegram.MessageEntity, error) {
+	switch mode {
+	case "":
+		return markup, nil, nil
+	case HTML:
+		return ParseHTML(markup)
+	case MarkdownV2:
+		return ParseMarkdownV2(markup)
+	}
+	return "", nil, fmt.Errorf("%w: parse mode '%s' is not supported", ErrInvalidMarkup, mode)
+}
+
+// entityParser
+//
+// Text and entities collected by the parsers, the entities are kept in the opening order
+type entityParser struct {
+	sb       strings.Builder
+	length   int
+	entities []telegram.MessageEntity
+	open     []int
+}
+
+func (p *entityParser) write(text string) {
+	p.sb.WriteString(text)
+	p.length += UTF16Len(text)
+}
+
+func (p *entityParser) push(e telegram.MessageEntity) {
+	e.Offset = p.length
+	p.open = append(p.open, len(p.entities))
+	p.entities = append(p.entities, e)
+}
+
+func (p *entityParser) top() *telegram.MessageEntity {
+	if len(p.open) == 0 {
+		return nil
+	}
+	return &p.entities[p.open[len(p.open)-1]]
+}
+
+func (p *entityParser) pop() {
+	e := p.top()
+	e.Length = p.length - e.Offset
+	p.open = p.open[:len(p.open)-1]
+}
+
+func (p *entityParser) result() (string, []telegram.MessageEntity) {
+	var entities []telegram.MessageEntity
+	for _, e := range p.entities {
+		if e.Length > 0 && e.Type != "" {
+			entities = append(entities, e)
+		}
+	}
+	return p.sb.String(), entities
+}
+
+// ParseHTML
+//
+// Text and entities of the Telegram HTML markup
+func ParseHTML(markup string) (string, []telegram.MessageEntity, error) {
+	p := entityParser{}
+	var tags []string
+	for i := 0; i < len(markup); {
+		switch markup[i] {
+		case '<':
+			j := strings.IndexByte(markup[i:], '>')
+			if j < 0 {
+				return "", nil, fmt.Errorf("%w: unclosed tag at %d", ErrInvalidMarkup, i)
+			}
+			tag := markup[i+1 : i+j]
+			i += j + 1
+			if strings.HasPrefix(tag, "/") {
+				name := strings.ToLower(strings.TrimSpace(tag[1:]))
+				if len(tags) == 0 || tags[len(tags)-1] != name {
+					return "", nil, fmt.Errorf("%w: unexpected closing tag '%s'", ErrInvalidMarkup, name)
+				}
+				tags = tags[:len(tags)-1]
+				p.pop()
+				continue
+			}
+			name, attrs, _ := strings.Cut(strings.TrimSpace(tag), " ")
+			name = strings.ToLower(name)
+			e, err := htmlEntity(name, attrs)
+			if err != nil {
+				return "", nil, err
+			}
+			// The code of a pre block sets the language of the block
+			if top := p.top(); name == "code" && top != nil && top.Type == Pre && top.Offset == p.length {
+				top.Language = e.Language
+				e = telegram.MessageEntity{}
+			}
+			// Only the code of a pre block has a language
+			e.Language = ""
+			tags = append(tags, name)
+			p.push(e)
+		case '&':
+			j := strings.IndexByte(markup[i:], ';')
+			if j < 0 {
+				p.write("&")
+				i++
+				continue
+			}
+			if r, ok := htmlCharacter(markup[i+1 : i+j]); ok {
+				p.write(string(r))
+				i += j + 1
+			} else {
+				p.write("&")
+				i++
+			}
+		default:
+			r, size := utf8.DecodeRuneInString(markup[i:])
+			p.write(string(r))
+			i += size
+		}
+	}
+	if len(tags) > 0 {
+		return "", nil, fmt.Errorf("%w: unclosed tag '%s'", ErrInvalidMarkup, tags[len(tags)-1])
+	}
+	text, entities := p.result()
+	return text, entities, nil
+}
+
+func htmlEntity(name string, attrs string) (telegram.MessageEntity, error) {
+	values := map[string]string{}
+	for _, m := range htmlAttrRegexp.FindAllStringSubmatch(attrs, -1) {
+		values[strings.ToLower(m[1])] = htmlUnescape(m[2] + m[3])
+	}
+	switch name {
+	case "b", "strong":
+		return telegram.MessageEntity{Type: Bold}, nil
+	case "i", "em":
+		return telegram.MessageEntity{Type: Italic}, nil
+	case "u", "ins":
+		return telegram.MessageEntity{Type: Underline}, nil
+	case "s", "strike", "del":
+		return telegram.MessageEntity{Type: Strikethrough}, nil
+	case "tg-spoiler":
+		return telegram.MessageEntity{Type: Spoiler}, nil
+	case "span":
+		if values["class"] == "tg-spoiler" {
+			return telegram.MessageEntity{Type: Spoiler}, nil
+		}
+	case "blockquote":
+		return telegram.MessageEntity{Type: Blockquote}, nil
+	case "pre":
+		return telegram.MessageEntity{Type: Pre}, nil
+	case "code":
+		return telegram.MessageEntity{Type: Code, Language: strings.TrimPrefix(values["class"], "language-")}, nil
+	case "a":
+		if strings.HasPrefix(values["href"], mentionURL) {
+			id, err := strconv.Atoi(values["href"][len(mentionURL):])
+			if err != nil {
+				return telegram.MessageEntity{}, fmt.Errorf("%w: invalid mention '%s'", ErrInvalidMarkup, values["href"])
+			}
+			return telegram.MessageEntity{Type: TextMention, User: &telegram.User{Id: id}}, nil
+		}
+		return telegram.MessageEntity{Type: TextLink, Url: values["href"]}, nil
+	}
+	return telegram.MessageEntity{}, fmt.Errorf("%w: unsupported tag '%s'", ErrInvalidMarkup, name)
+}
+
+// htmlCharacter
+//
+// Character of the named or numeric HTML entity supported by Telegram
+func htmlCharacter(name string) (rune, bool) {
+	switch name {
+	case "lt":
+		return '<', true
+	case "gt":
+		return '>', true
+	case "amp":
+		return '&', true
+	case "quot":
+		return '"', true
+	}
+	if !strings.HasPrefix(name, "#") {
+		return 0, false
+	}
+	base := 10
+	digits := name[1:]
+	if strings.HasPrefix(digits, "x") || strings.HasPrefix(digits, "X") {
+		base, digits = 16, digits[1:]
+	}
+	n, err := strconv.ParseUint(digits, base, 32)
+	if err != nil || !utf8.ValidRune(rune(n)) {
+		return 0, false
+	}
+	return rune(n), true
+}
+
+func htmlUnescape(s string) string {
+	text, _, err := ParseHTML(s)
+	if err != nil {
+		return s
+	}
+	return text
+}
+
+// ParseMarkdownV2
+//
+// Text and entities of the Telegram MarkdownV2 markup
+func ParseMarkdownV2(markup string) (string, []telegram.MessageEntity, error) {
+	p := entityParser{}
+	quote := false
+	for i := 0; i < len(markup); {
+		top := p.top()
+		inCode := top != nil && (top.Type == Code || top.Type == Pre)
+		lineStart := i == 0 || markup[i-1] == '\n'
+		switch {
+		case markup[i] == '\\' && i+1 < len(markup):
+			r, size := utf8.DecodeRuneInString(markup[i+1:])
+			p.write(string(r))
+			i += 1 + size
+		case strings.HasPrefix(markup[i:], "```") && (!inCode || top.Type == Pre):
+			if inCode {
+				// The line break before the closing backticks isn't a part of the block
+				if text := p.sb.String(); strings.HasSuffix(text, "\n") && p.length > top.Offset {
+					p.sb.Reset()
+					p.sb.WriteString(text[:len(text)-1])
+					p.length--
+				}
+				p.pop()
+				i += 3
+				continue
+			}
+			i += 3
+			language := ""
+			if j := strings.IndexByte(markup[i:], '\n'); j >= 0 {
+				language = strings.TrimSpace(markup[i : i+j])
+				i += j + 1
+			}
+			p.push(telegram.MessageEntity{Type: Pre, Language: language})
+		case markup[i] == '`' && (!inCode || top.Type == Code):
+			if err := markdownToggle(&p, Code); err != nil {
+				return "", nil, err
+			}
+			i++
+		case inCode:
+			r, size := utf8.DecodeRuneInString(markup[i:])
+			p.write(string(r))
+			i += size
+		case markup[i] == '>' && lineStart:
+			if !quote {
+				p.push(telegram.MessageEntity{Type: Blockquote})
+				quote = true
+			}
+			i++
+		case markup[i] == '\n' && quote && !strings.HasPrefix(markup[i+1:], ">"):
+			if top.Type != Blockquote {
+				return "", nil, fmt.Errorf("%w: entity '%s' isn't closed in the quote", ErrInvalidMarkup, top.Type)
+			}
+			p.pop()
+			quote = false
+			p.write("\n")
+			i++
+		case strings.HasPrefix(markup[i:], "||") || strings.HasPrefix(markup[i:], "__"):
+			t := map[byte]string{'|': Spoiler, '_': Underline}[markup[i]]
+			if err := markdownToggle(&p, t); err != nil {
+				return "", nil, err
+			}
+			i += 2
+		case markup[i] == '*' || markup[i] == '_' || markup[i] == '~':
+			t := map[byte]string{'*': Bold, '_': Italic, '~': Strikethrough}[markup[i]]
+			if err := markdownToggle(&p, t); err != nil {
+				return "", nil, err
+			}
+			i++
+		case markup[i] == '[':
+			p.push(telegram.MessageEntity{Type: TextLink})
+			i++
+		case markup[i] == ']' && top != nil && top.Type == TextLink && strings.HasPrefix(markup[i+1:], "("):
+			url, n := markdownURL(markup[i+2:])
+			if n < 0 {
+				return "", nil, fmt.Errorf("%w: unclosed link url at %d", ErrInvalidMarkup, i)
+			}
+			top.Url = url
+			if strings.HasPrefix(url, mentionURL) {
+				if id, err := strconv.Atoi(url[len(mentionURL):]); err == nil {
+					*top = telegram.MessageEntity{Type: TextMention, Offset: top.Offset, User: &telegram.User{Id: id}}
+				}
+			}
+			p.pop()
+			i += 2 + n
+		default:
+			r, size := utf8.DecodeRuneInString(markup[i:])
+			p.write(string(r))
+			i += size
+		}
+	}
+	if quote && p.top().Type == Blockquote {
+		p.pop()
+	}
+	if top := p.top(); top != nil {
+		return "", nil, fmt.Errorf("%w: entity '%s' isn't closed", ErrInvalidMarkup, top.Type)
+	}
+	text, entities := p.result()
+	return text, entities, nil
+}
+
+// markdownToggle
+//
+// Close the entity of the type if it is the innermost one or open a new one
+func markdownToggle(p *entityParser, t string) error {
+	if top := p.top(); top != nil && top.Type == t {
+		p.pop()
+		return nil
+	}
+	for _, i := range p.open {
+		if p.entities[i].Type == t {
+			return fmt.Errorf("%w: entity '%s' is closed before the nested ones", ErrInvalidMarkup, t)
+		}
+	}
+	p.push(telegram.MessageEntity{Type: t})
+	return nil
+}
+
+// markdownURL
+//
+// Unescaped link url and the length of the markup up to the closing parenthesis, -1 without one
+func markdownURL(markup string) (string, int) {
+	var sb strings.Builder
+	for i := 0; i < len(markup); i++ {
+		switch markup[i] {
+		case '\\':
+			if i+1 < len(markup) {
+				i++
+				sb.WriteByte(markup[i])
+			}
+		case ')':
+			return sb.String(), i + 1
+		default:
+			sb.WriteByte(markup[i])
+		}
+	}
+	return "", -1
+}
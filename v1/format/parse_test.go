@@ -0,0 +1,171 @@
+package format
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alex13th/telebot/v1/telegram"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		markup   string
+		text     string
+		entities []telegram.MessageEntity
+		wantErr  error
+	}{
+		{
+			name:   "Nested",
+			markup: `👍 <b>bold <i>italic</i></b> &lt;&amp;&gt; <a href="https://example.com/?a=1&amp;b=2">link</a>`,
+			text:   "👍 bold italic <&> link",
+			entities: []telegram.MessageEntity{
+				{Type: "bold", Offset: 3, Length: 11},
+				{Type: "italic", Offset: 8, Length: 6},
+				{Type: "text_link", Offset: 19, Length: 4, Url: "https://example.com/?a=1&b=2"},
+			},
+		},
+		{
+			name:   "Aliases",
+			markup: `<strong>a</strong><em>b</em><ins>c</ins><del>d</del><span class="tg-spoiler">e</span><tg-spoiler>f</tg-spoiler>`,
+			text:   "abcdef",
+			entities: []telegram.MessageEntity{
+				{Type: "bold", Offset: 0, Length: 1},
+				{Type: "italic", Offset: 1, Length: 1},
+				{Type: "underline", Offset: 2, Length: 1},
+				{Type: "strikethrough", Offset: 3, Length: 1},
+				{Type: "spoiler", Offset: 4, Length: 1},
+				{Type: "spoiler", Offset: 5, Length: 1},
+			},
+		},
+		{
+			name:   "Code",
+			markup: `<code>a&lt;b</code> <pre><code class="language-go">x := 1</code></pre><pre>y</pre>`,
+			text:   "a<b x := 1y",
+			entities: []telegram.MessageEntity{
+				{Type: "code", Offset: 0, Length: 3},
+				{Type: "pre", Offset: 4, Length: 6, Language: "go"},
+				{Type: "pre", Offset: 10, Length: 1},
+			},
+		},
+		{
+			name:   "Mention",
+			markup: `<a href="tg://user?id=123">User</a> &#128077; AT&T`,
+			text:   "User 👍 AT&T",
+			entities: []telegram.MessageEntity{
+				{Type: "text_mention", Offset: 0, Length: 4, User: &telegram.User{Id: 123}},
+			},
+		},
+		{name: "Unclosed", markup: "<b>bold", wantErr: ErrInvalidMarkup},
+		{name: "Crossing", markup: "<b><i>a</b></i>", wantErr: ErrInvalidMarkup},
+		{name: "Unsupported", markup: "<div>a</div>", wantErr: ErrInvalidMarkup},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, entities, err := ParseHTML(tt.markup)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseHTML() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if text != tt.text {
+				t.Errorf("ParseHTML() text = %q, want %q", text, tt.text)
+			}
+			if diff := cmp.Diff(tt.entities, entities); diff != "" {
+				t.Errorf("ParseHTML() entities mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name     string
+		markup   string
+		text     string
+		entities []telegram.MessageEntity
+		wantErr  error
+	}{
+		{
+			name:   "Styles",
+			markup: "*bold _italic_* __under__ ~strike~ ||spoiler|| 1\\.5\\!",
+			text:   "bold italic under strike spoiler 1.5!",
+			entities: []telegram.MessageEntity{
+				{Type: "bold", Offset: 0, Length: 11},
+				{Type: "italic", Offset: 5, Length: 6},
+				{Type: "underline", Offset: 12, Length: 5},
+				{Type: "strikethrough", Offset: 18, Length: 6},
+				{Type: "spoiler", Offset: 25, Length: 7},
+			},
+		},
+		{
+			name:   "Code",
+			markup: "`a*b\\`` ```go\nx := `1`*\n```",
+			text:   "a*b` x := `1`*",
+			entities: []telegram.MessageEntity{
+				{Type: "code", Offset: 0, Length: 4},
+				{Type: "pre", Offset: 5, Length: 9, Language: "go"},
+			},
+		},
+		{
+			name:   "Links",
+			markup: "[site 👍](https://example.com/a_(b\\)) [User](tg://user?id=123)",
+			text:   "site 👍 User",
+			entities: []telegram.MessageEntity{
+				{Type: "text_link", Offset: 0, Length: 7, Url: "https://example.com/a_(b)"},
+				{Type: "text_mention", Offset: 8, Length: 4, User: &telegram.User{Id: 123}},
+			},
+		},
+		{
+			name:   "Blockquote",
+			markup: ">line *1*\n>line 2\nend a\\>b",
+			text:   "line 1\nline 2\nend a>b",
+			entities: []telegram.MessageEntity{
+				{Type: "blockquote", Offset: 0, Length: 13},
+				{Type: "bold", Offset: 5, Length: 1},
+			},
+		},
+		{name: "Unclosed", markup: "*bold", wantErr: ErrInvalidMarkup},
+		{name: "Crossing", markup: "*a _b* c_", wantErr: ErrInvalidMarkup},
+		{name: "Unclosed url", markup: "[a](http://a", wantErr: ErrInvalidMarkup},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, entities, err := ParseMarkdownV2(tt.markup)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseMarkdownV2() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if text != tt.text {
+				t.Errorf("ParseMarkdownV2() text = %q, want %q", text, tt.text)
+			}
+			if diff := cmp.Diff(tt.entities, entities); diff != "" {
+				t.Errorf("ParseMarkdownV2() entities mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParse_Builder(t *testing.T) {
+	build := func(b *Builder) string {
+		return b.Text("Hi 👍 ").Bold("a.b").Italic("c<d>").Underline("u").Strikethrough("s").Spoiler("x").
+			Code("co`de").Text(" ").Link("l(i)nk", "https://example.com/(a)").Mention("U", 1).Pre("x\n`y`", "go").
+			String()
+	}
+	want := NewEntities().Text("Hi 👍 ").Bold("a.b").Italic("c<d>").Underline("u").Strikethrough("s").Spoiler("x").
+		Code("co`de").Text(" ").Link("l(i)nk", "https://example.com/(a)").Mention("U", 1).Pre("x\n`y`", "go")
+	for _, b := range []*Builder{NewHTML(), NewMarkdownV2()} {
+		t.Run(string(b.Mode), func(t *testing.T) {
+			text, entities, err := Parse(b.Mode, build(b))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if text != want.String() {
+				t.Errorf("Parse() text = %q, want %q", text, want.String())
+			}
+			if diff := cmp.Diff(want.Entities(), entities); diff != "" {
+				t.Errorf("Parse() entities mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package format
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alex13th/telebot/v1/telegram"
+)
+
+// Sender
+//
+// Sender of the messages longer than Limit. A long text is parsed with its parse mode
+// and the chunks of the Split are sent with the entities. Only the first chunk replies
+// to the message and only the last one gets the reply markup.
+// Copy splits a caption longer than MaxCaptionLength the same way, the rest
+// of the caption follows the media in text messages.
+type Sender struct {
+	Limit int
+	bot   telegram.Bot
+}
+
+func NewSender(b telegram.Bot) Sender {
+	return Sender{Limit: MaxMessageLength, bot: b}
+}
+
+// Send
+//
+// Send the message as one or more messages and return the sent ones.
+// The messages sent before an error are returned with the error.
+func (s Sender) Send(ctx context.Context, msg telegram.SendMessage) ([]telegram.Message, error) {
+	limit := s.Limit
+	if limit <= 0 {
+		limit = MaxMessageLength
+	}
+	// The visible text of a markup is never longer than the markup
+	if UTF16Len(msg.Text) <= limit {
+		resp, err := s.send(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+		return []telegram.Message{resp.Result}, nil
+	}
+	text, entities := msg.Text, msg.Entities
+	if msg.ParseMode != "" {
+		var err error
+		if text, entities, err = Parse(Mode(msg.ParseMode), msg.Text); err != nil {
+			return nil, err
+		}
+	}
+
+	chunks := Split(text, entities, limit)
+	return s.sendChunks(ctx, msg, chunks, make([]telegram.Message, 0, len(chunks)))
+}
+
+// Copy
+//
+// Copy the media message with the caption cut to MaxCaptionLength and send the rest
+// of the caption as text messages, the sent messages are returned.
+// The messages sent before an error are returned with the error.
+func (s Sender) Copy(ctx context.Context, msg telegram.CopyMessage) ([]telegram.Message, error) {
+	if msg.Caption == nil || UTF16Len(*msg.Caption) <= MaxCaptionLength {
+		resp, err := s.send(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+		return []telegram.Message{resp.Result}, nil
+	}
+	text, entities := *msg.Caption, msg.CaptionEntities
+	if msg.ParseMode != "" {
+		var err error
+		if text, entities, err = Parse(Mode(msg.ParseMode), text); err != nil {
+			return nil, err
+		}
+	}
+
+	caption, chunks := SplitCaption(text, entities)
+	media := msg
+	media.Caption, media.CaptionEntities, media.ParseMode = &caption.Text, caption.Entities, ""
+	if len(chunks) > 0 {
+		media.ReplyMarkup = nil
+	}
+	resp, err := s.send(ctx, media)
+	if err != nil {
+		return nil, fmt.Errorf("send part %d of %d error: '%w'", 1, len(chunks)+1, err)
+	}
+	rest := telegram.SendMessage{ChatId: msg.ChatId, DisableNotification: msg.DisableNotification, ReplyMarkup: msg.ReplyMarkup}
+	sent := make([]telegram.Message, 1, len(chunks)+1)
+	sent[0] = resp.Result
+	return s.sendChunks(ctx, rest, chunks, sent)
+}
+
+// sendChunks
+//
+// Send the chunks as the text of the message after the already sent messages
+func (s Sender) sendChunks(ctx context.Context, msg telegram.SendMessage, chunks []Chunk, sent []telegram.Message) ([]telegram.Message, error) {
+	total := len(sent) + len(chunks)
+	for i, c := range chunks {
+		part := msg
+		part.Text, part.Entities, part.ParseMode = c.Text, c.Entities, ""
+		if i > 0 {
+			part.ReplyToMessageId = 0
+		}
+		if i < len(chunks)-1 {
+			part.ReplyMarkup = nil
+		}
+		resp, err := s.send(ctx, part)
+		if err != nil {
+			return sent, fmt.Errorf("send part %d of %d error: '%w'", len(sent)+1, total, err)
+		}
+		sent = append(sent, resp.Result)
+	}
+	return sent, nil
+}
+
+func (s Sender) send(ctx context.Context, msg telegram.Request) (telegram.MessageResponse, error) {
+	resp, err := s.bot.Send(ctx, msg)
+	if err != nil {
+		return resp, err
+	}
+	if !resp.Ok {
+		return resp, telegram.ErrStatus{ErrorCode: resp.ErrorCode, Description: resp.Description}
+	}
+	return resp, nil
+}
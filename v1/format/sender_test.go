@@ -0,0 +1,155 @@
+package format
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alex13th/telebot/v1/telegram"
+	"github.com/google/go-cmp/cmp"
+)
+
+type botMock struct {
+	requests []telegram.Request
+	failAt   int
+	err      error
+}
+
+func (bm *botMock) GetUpdates(ctx context.Context, ur telegram.UpdatesRequest) (telegram.UpdateResponse, error) {
+	return telegram.UpdateResponse{}, bm.err
+}
+
+func (bm *botMock) Send(ctx context.Context, r telegram.Request) (telegram.MessageResponse, error) {
+	bm.requests = append(bm.requests, r)
+	if len(bm.requests) == bm.failAt {
+		return telegram.MessageResponse{ErrorCode: 400, Description: "Bad Request"}, bm.err
+	}
+	return telegram.MessageResponse{Ok: true, Result: telegram.Message{MessageId: len(bm.requests)}}, nil
+}
+
+func TestSender_Send(t *testing.T) {
+	kbd := telegram.InlineKeyboardMarkup{InlineKeyboard: [][]telegram.InlineKeyboardButton{{{Text: "Ok", CallbackData: "ok"}}}}
+	tests := []struct {
+		name string
+		msg  telegram.SendMessage
+		want []telegram.Request
+	}{
+		{
+			name: "Short",
+			msg:  telegram.SendMessage{ChatId: 1, Text: "<b>short</b>", ParseMode: "HTML", ReplyMarkup: kbd},
+			want: []telegram.Request{
+				telegram.SendMessage{ChatId: 1, Text: "<b>short</b>", ParseMode: "HTML", ReplyMarkup: kbd},
+			},
+		},
+		{
+			name: "HTML",
+			msg: telegram.SendMessage{ChatId: 1, ParseMode: "HTML", ReplyToMessageId: 5, ReplyMarkup: kbd,
+				Text: "<b>first &amp; part</b>\n\n<i>second part</i> end"},
+			want: []telegram.Request{
+				telegram.SendMessage{ChatId: 1, Text: "first & part", ReplyToMessageId: 5,
+					Entities: []telegram.MessageEntity{{Type: "bold", Offset: 0, Length: 12}}},
+				telegram.SendMessage{ChatId: 1, Text: "second part end", ReplyMarkup: kbd,
+					Entities: []telegram.MessageEntity{{Type: "italic", Offset: 0, Length: 11}}},
+			},
+		},
+		{
+			name: "MarkdownV2",
+			msg:  telegram.SendMessage{ChatId: 1, ParseMode: "MarkdownV2", Text: "*bold\\.* text\n_italic_ end"},
+			want: []telegram.Request{
+				telegram.SendMessage{ChatId: 1, Text: "bold. text",
+					Entities: []telegram.MessageEntity{{Type: "bold", Offset: 0, Length: 5}}},
+				telegram.SendMessage{ChatId: 1, Text: "italic end",
+					Entities: []telegram.MessageEntity{{Type: "italic", Offset: 0, Length: 6}}},
+			},
+		},
+		{
+			name: "Entities",
+			msg: telegram.SendMessage{ChatId: 1, Text: "👍 code block and more",
+				Entities: []telegram.MessageEntity{{Type: "code", Offset: 3, Length: 10}}},
+			want: []telegram.Request{
+				telegram.SendMessage{ChatId: 1, Text: "👍 code block",
+					Entities: []telegram.MessageEntity{{Type: "code", Offset: 3, Length: 10}}},
+				telegram.SendMessage{ChatId: 1, Text: "and more"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bm := &botMock{}
+			s := NewSender(bm)
+			s.Limit = 16
+			sent, err := s.Send(context.Background(), tt.msg)
+			if err != nil {
+				t.Fatalf("Sender.Send() error = %v", err)
+			}
+			if len(sent) != len(tt.want) || sent[len(sent)-1].MessageId != len(tt.want) {
+				t.Errorf("Sender.Send() sent = %v, want %d messages", sent, len(tt.want))
+			}
+			if diff := cmp.Diff(tt.want, bm.requests); diff != "" {
+				t.Errorf("Sender.Send() requests mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSender_Send_Error(t *testing.T) {
+	text := strings.Repeat("word ", 10)
+	bm := &botMock{failAt: 2, err: errors.New("send error")}
+	s := NewSender(bm)
+	s.Limit = 10
+	sent, err := s.Send(context.Background(), telegram.SendMessage{ChatId: 1, Text: text})
+	if !errors.Is(err, bm.err) || len(sent) != 1 {
+		t.Errorf("Sender.Send() = %v, error = %v, wantErr %v", sent, err, bm.err)
+	}
+
+	bm = &botMock{failAt: 1}
+	_, err = NewSender(bm).Send(context.Background(), telegram.SendMessage{ChatId: 1, Text: text})
+	var status telegram.ErrStatus
+	if !errors.As(err, &status) || status.ErrorCode != 400 {
+		t.Errorf("Sender.Send() error = %v, want status error", err)
+	}
+
+	bm = &botMock{}
+	_, err = NewSender(bm).Send(context.Background(), telegram.SendMessage{ChatId: 1, Text: "<b>" + strings.Repeat("a", 5000), ParseMode: "HTML"})
+	if !errors.Is(err, ErrInvalidMarkup) || len(bm.requests) != 0 {
+		t.Errorf("Sender.Send() error = %v, wantErr %v", err, ErrInvalidMarkup)
+	}
+}
+
+func TestSender_Copy(t *testing.T) {
+	kbd := telegram.InlineKeyboardMarkup{InlineKeyboard: [][]telegram.InlineKeyboardButton{{{Text: "Ok", CallbackData: "ok"}}}}
+	short := "<b>short</b>"
+	bm := &botMock{}
+	msg := telegram.CopyMessage{ChatId: 1, FromChatId: 2, MessageId: 3, Caption: &short, ParseMode: "HTML", ReplyMarkup: kbd}
+	if _, err := NewSender(bm).Copy(context.Background(), msg); err != nil {
+		t.Errorf("Sender.Copy() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff([]telegram.Request{msg}, bm.requests); diff != "" {
+		t.Errorf("Sender.Copy() requests mismatch (-want +got):\n%s", diff)
+	}
+
+	words := strings.Repeat("word ", 250)
+	long := "<i>" + words + "</i>"
+	bm = &botMock{}
+	msg = telegram.CopyMessage{ChatId: 1, FromChatId: 2, MessageId: 3, Caption: &long, ParseMode: "HTML", ReplyToMessageId: 5, ReplyMarkup: kbd}
+	sent, err := NewSender(bm).Copy(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("Sender.Copy() error = %v", err)
+	}
+	if len(sent) != 2 || len(bm.requests) != 2 {
+		t.Fatalf("Sender.Copy() sent = %v, want 2 messages", sent)
+	}
+	media, ok := bm.requests[0].(telegram.CopyMessage)
+	if !ok || media.ReplyMarkup != nil || media.ParseMode != "" || media.ReplyToMessageId != 5 ||
+		UTF16Len(*media.Caption) > MaxCaptionLength || len(media.CaptionEntities) != 1 {
+		t.Errorf("Sender.Copy() media request = %v", bm.requests[0])
+	}
+	rest, ok := bm.requests[1].(telegram.SendMessage)
+	if !ok || rest.ChatId != 1 || rest.ReplyToMessageId != 0 || rest.ReplyMarkup == nil || len(rest.Entities) != 1 {
+		t.Errorf("Sender.Copy() text request = %v", bm.requests[1])
+	}
+	if got := *media.Caption + " " + rest.Text; got != words {
+		t.Errorf("Sender.Copy() text = %q, want %q", got, words)
+	}
+}
@@ -0,0 +1,130 @@
+package format
+
+import (
+	"unicode/utf16"
+
+	"github.com/alex13th/telebot/v1/telegram"
+)
+
+const (
+	MaxMessageLength = 4096
+	MaxCaptionLength = 1024
+)
+
+// Chunk
+//
+// Part of a split text with the entities rebased to the part
+type Chunk struct {
+	Text     string
+	Entities []telegram.MessageEntity
+}
+
+// Split
+//
+// Split the text into chunks of at most limit UTF-16 code units.
+// A chunk is cut at the last paragraph, line or word boundary outside the entities,
+// an entity is cut only when no boundary outside it fits the chunk.
+// The whitespace at the cuts is dropped. A limit below 2 can't fit a surrogate pair,
+// such a pair makes a chunk of its own.
+func Split(text string, entities []telegram.MessageEntity, limit int) []Chunk {
+	if limit <= 0 {
+		limit = MaxMessageLength
+	}
+	units := utf16.Encode([]rune(text))
+	var chunks []Chunk
+	for start := 0; start < len(units); {
+		end, next := splitPoint(units, entities, start, limit)
+		chunks = append(chunks, chunk(units, entities, start, end))
+		start = next
+	}
+	return chunks
+}
+
+// SplitCaption
+//
+// Split the caption of a media message, the caption is cut to MaxCaptionLength
+// and the rest is split into the chunks of the following text messages
+func SplitCaption(text string, entities []telegram.MessageEntity) (Chunk, []Chunk) {
+	units := utf16.Encode([]rune(text))
+	end, next := splitPoint(units, entities, 0, MaxCaptionLength)
+	rest := chunk(units, entities, next, len(units))
+	return chunk(units, entities, 0, end), Split(rest.Text, rest.Entities, MaxMessageLength)
+}
+
+// splitPoint
+//
+// End of the chunk starting at start and the start of the next chunk
+func splitPoint(units []uint16, entities []telegram.MessageEntity, start int, limit int) (int, int) {
+	if len(units)-start <= limit {
+		return len(units), len(units)
+	}
+	boundaries := []func(p int) bool{
+		func(p int) bool { return units[p] == '\n' && p+1 < len(units) && units[p+1] == '\n' },
+		func(p int) bool { return units[p] == '\n' },
+		func(p int) bool { return isSpace(units[p]) },
+	}
+	for _, cutEntities := range []bool{false, true} {
+		for _, boundary := range boundaries {
+			for p := start + limit; p > start; p-- {
+				if !boundary(p) || (!cutEntities && insideEntity(entities, p)) {
+					continue
+				}
+				end := p
+				for end > start && isSpace(units[end-1]) {
+					end--
+				}
+				if end == start {
+					break
+				}
+				next := p
+				for next < len(units) && isSpace(units[next]) {
+					next++
+				}
+				return end, next
+			}
+		}
+	}
+	cut := start + limit
+	// A surrogate pair isn't cut, the pair longer than the limit is kept whole to make progress
+	if units[cut] >= 0xdc00 && units[cut] < 0xe000 {
+		cut--
+		if cut == start {
+			cut += 2
+		}
+	}
+	return cut, cut
+}
+
+// chunk
+//
+// Chunk of the units from start to end with the entities cut to the chunk
+func chunk(units []uint16, entities []telegram.MessageEntity, start int, end int) Chunk {
+	c := Chunk{Text: string(utf16.Decode(units[start:end]))}
+	for _, e := range entities {
+		from, to := e.Offset, e.Offset+e.Length
+		if from < start {
+			from = start
+		}
+		if to > end {
+			to = end
+		}
+		if to > from {
+			e.Offset, e.Length = from-start, to-from
+			c.Entities = append(c.Entities, e)
+		}
+	}
+	return c
+}
+
+func insideEntity(entities []telegram.MessageEntity, p int) bool {
+	for _, e := range entities {
+		if e.Offset < p && p < e.Offset+e.Length {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpace(u uint16) bool {
+	return u == ' ' || u == '\n' || u == '\t' || u == '\r'
+}
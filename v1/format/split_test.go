@@ -0,0 +1,124 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alex13th/telebot/v1/telegram"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		entities []telegram.MessageEntity
+		limit    int
+		want     []Chunk
+	}{
+		{
+			name:  "Short",
+			text:  "short text",
+			limit: 20,
+			want:  []Chunk{{Text: "short text"}},
+		},
+		{
+			name:  "Paragraph",
+			text:  "first line\nsecond\n\nthird line",
+			limit: 22,
+			want:  []Chunk{{Text: "first line\nsecond"}, {Text: "third line"}},
+		},
+		{
+			name:  "Line",
+			text:  "first line\nsecond line",
+			limit: 15,
+			want:  []Chunk{{Text: "first line"}, {Text: "second line"}},
+		},
+		{
+			name:  "Surrogate pair over limit",
+			text:  "😀😀a",
+			limit: 1,
+			want:  []Chunk{{Text: "😀"}, {Text: "😀"}, {Text: "a"}},
+		},
+		{
+			name:  "Word",
+			text:  "one two three four",
+			limit: 9,
+			want:  []Chunk{{Text: "one two"}, {Text: "three"}, {Text: "four"}},
+		},
+		{
+			name:  "Hard",
+			text:  "abcdefghij",
+			limit: 4,
+			want:  []Chunk{{Text: "abcd"}, {Text: "efgh"}, {Text: "ij"}},
+		},
+		{
+			name:  "Surrogate",
+			text:  "ab👍cd",
+			limit: 3,
+			want:  []Chunk{{Text: "ab"}, {Text: "👍c"}, {Text: "d"}},
+		},
+		{
+			name:     "Entity",
+			text:     "aa bb cc dd",
+			entities: []telegram.MessageEntity{{Type: "bold", Offset: 3, Length: 5}, {Type: "italic", Offset: 9, Length: 2}},
+			limit:    7,
+			want: []Chunk{
+				{Text: "aa"},
+				{Text: "bb cc", Entities: []telegram.MessageEntity{{Type: "bold", Offset: 0, Length: 5}}},
+				{Text: "dd", Entities: []telegram.MessageEntity{{Type: "italic", Offset: 0, Length: 2}}},
+			},
+		},
+		{
+			name:     "Long entity",
+			text:     "👍 aa bb cc",
+			entities: []telegram.MessageEntity{{Type: "bold", Offset: 0, Length: 11}},
+			limit:    6,
+			want: []Chunk{
+				{Text: "👍 aa", Entities: []telegram.MessageEntity{{Type: "bold", Offset: 0, Length: 5}}},
+				{Text: "bb cc", Entities: []telegram.MessageEntity{{Type: "bold", Offset: 0, Length: 5}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.text, tt.entities, tt.limit)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Split() mismatch (-want +got):\n%s", diff)
+			}
+			for _, c := range got {
+				// Only a single character over the limit makes a longer chunk
+				if UTF16Len(c.Text) > tt.limit && len([]rune(c.Text)) > 1 {
+					t.Errorf("Split() chunk length = %d, limit %d", UTF16Len(c.Text), tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitCaption(t *testing.T) {
+	text := strings.Repeat("a", 1000) + " " + strings.Repeat("b", 100) + "\n\n" + strings.Repeat("c ", 2500)
+	caption, rest := SplitCaption(text, []telegram.MessageEntity{{Type: "bold", Offset: 1001, Length: 10}})
+	if caption.Text != strings.Repeat("a", 1000) || len(caption.Entities) != 0 {
+		t.Errorf("SplitCaption() caption = %v", caption)
+	}
+	if len(rest) != 3 {
+		t.Fatalf("SplitCaption() rest chunks = %d, want 3", len(rest))
+	}
+	if diff := cmp.Diff([]telegram.MessageEntity{{Type: "bold", Offset: 0, Length: 10}}, rest[0].Entities); diff != "" {
+		t.Errorf("SplitCaption() rest entities mismatch (-want +got):\n%s", diff)
+	}
+	if rest[0].Text != strings.Repeat("b", 100) {
+		t.Errorf("SplitCaption() rest text = %v, want the paragraph", rest[0].Text)
+	}
+	for _, c := range rest[1:] {
+		if UTF16Len(c.Text) > MaxMessageLength || !strings.HasPrefix(c.Text, "c c") {
+			t.Errorf("SplitCaption() rest text length = %d", UTF16Len(c.Text))
+		}
+	}
+
+	caption, rest = SplitCaption("short", nil)
+	if caption.Text != "short" || rest != nil {
+		t.Errorf("SplitCaption() = %v, %v, want short caption", caption, rest)
+	}
+}
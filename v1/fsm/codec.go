@@ -0,0 +1,201 @@
+package fsm
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxCallbackDataLength
+//
+// Telegram limit of the callback_data of an inline keyboard button in bytes
+const MaxCallbackDataLength = 64
+
+var (
+	ErrCallbackDataTooLong = errors.New("the callback data exceeds 64 bytes")
+	ErrInvalidCallbackData = errors.New("the callback data is invalid")
+)
+
+// Codec
+//
+// Encoding of a state to the callback data of a button and back
+type Codec interface {
+	Encode(st State) (string, error)
+	Decode(data string) (State, error)
+}
+
+// ValidateCallbackData
+//
+// Check the callback data fits the Telegram limit
+func ValidateCallbackData(data string) error {
+	if len(data) > MaxCallbackDataLength {
+		return fmt.Errorf("%w: %d bytes, data: %s", ErrCallbackDataTooLong, len(data), data)
+	}
+	return nil
+}
+
+// CallbackData
+//
+// Callback data of the state in the String form. Fields except the Value can't contain
+// the separator, the data must fit the Telegram limit.
+func (st State) CallbackData() (string, error) {
+	return PlainCodec{Separator: st.Separator}.Encode(st)
+}
+
+// PlainCodec
+//
+// State.String and State.Parse with validation
+type PlainCodec struct {
+	Separator string
+}
+
+func (c PlainCodec) Encode(st State) (string, error) {
+	st.Separator = c.Separator
+	for _, field := range []string{st.Prefix, st.State, st.Action, st.Key} {
+		if strings.Contains(field, c.Separator) {
+			return "", fmt.Errorf("%w: field '%s' contains separator '%s'", ErrInvalidCallbackData, field, c.Separator)
+		}
+	}
+	if st.Key == "" && st.Value != "" {
+		return "", fmt.Errorf("%w: value '%s' without key", ErrInvalidCallbackData, st.Value)
+	}
+	data := st.String()
+	return data, ValidateCallbackData(data)
+}
+
+func (c PlainCodec) Decode(data string) (State, error) {
+	return State{Separator: c.Separator}.Parse(data)
+}
+
+// EscapeCodec
+//
+// Fields are joined with the Separator, the separator and the escape character
+// inside the fields are escaped with a backslash, so any field can contain them
+type EscapeCodec struct {
+	Separator string
+}
+
+const escapeChar = `\`
+
+func (c EscapeCodec) Encode(st State) (string, error) {
+	if c.Separator == "" || c.Separator == escapeChar {
+		return "", fmt.Errorf("%w: separator '%s' can't be used", ErrInvalidCallbackData, c.Separator)
+	}
+	if st.Action == "" {
+		st.Action = st.State
+	}
+	fields := []string{st.Prefix, st.State, st.Action}
+	if st.Key != "" || st.Value != "" {
+		fields = append(fields, st.Key)
+	}
+	if st.Value != "" {
+		fields = append(fields, st.Value)
+	}
+	for i, field := range fields {
+		field = strings.ReplaceAll(field, escapeChar, escapeChar+escapeChar)
+		fields[i] = strings.ReplaceAll(field, c.Separator, escapeChar+c.Separator)
+	}
+	data := strings.Join(fields, c.Separator)
+	return data, ValidateCallbackData(data)
+}
+
+func (c EscapeCodec) Decode(data string) (State, error) {
+	var fields []string
+	var sb strings.Builder
+	for i := 0; i < len(data); {
+		switch {
+		case strings.HasPrefix(data[i:], escapeChar+escapeChar):
+			sb.WriteString(escapeChar)
+			i += 2 * len(escapeChar)
+		case strings.HasPrefix(data[i:], escapeChar+c.Separator):
+			sb.WriteString(c.Separator)
+			i += len(escapeChar) + len(c.Separator)
+		case strings.HasPrefix(data[i:], escapeChar):
+			return State{}, fmt.Errorf("%w: unknown escape at %d, data: %s", ErrInvalidCallbackData, i, data)
+		case strings.HasPrefix(data[i:], c.Separator):
+			fields = append(fields, sb.String())
+			sb.Reset()
+			i += len(c.Separator)
+		default:
+			sb.WriteByte(data[i])
+			i++
+		}
+	}
+	fields = append(fields, sb.String())
+	return stateFromFields(c.Separator, fields, data)
+}
+
+// CompactCodec
+//
+// The Prefix followed by the Separator and the base64url of the other fields,
+// each prefixed with its varint length. Fields can contain any bytes without escaping
+// and an Action equal to the State is stored as an empty one.
+type CompactCodec struct {
+	Separator string
+}
+
+func (c CompactCodec) Encode(st State) (string, error) {
+	if c.Separator == "" || strings.Contains(st.Prefix, c.Separator) {
+		return "", fmt.Errorf("%w: prefix '%s' contains separator '%s'", ErrInvalidCallbackData, st.Prefix, c.Separator)
+	}
+	if st.Action == st.State {
+		st.Action = ""
+	}
+	fields := []string{st.State, st.Action}
+	if st.Key != "" || st.Value != "" {
+		fields = append(fields, st.Key)
+	}
+	if st.Value != "" {
+		fields = append(fields, st.Value)
+	}
+	var buf []byte
+	size := make([]byte, binary.MaxVarintLen64)
+	for _, field := range fields {
+		n := binary.PutUvarint(size, uint64(len(field)))
+		buf = append(append(buf, size[:n]...), field...)
+	}
+	data := st.Prefix + c.Separator + base64.RawURLEncoding.EncodeToString(buf)
+	return data, ValidateCallbackData(data)
+}
+
+func (c CompactCodec) Decode(data string) (State, error) {
+	prefix, encoded, ok := strings.Cut(data, c.Separator)
+	if !ok {
+		return State{}, fmt.Errorf("%w: no prefix, data: %s", ErrInvalidCallbackData, data)
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return State{}, fmt.Errorf("%w: %v, data: %s", ErrInvalidCallbackData, err, data)
+	}
+	fields := []string{prefix}
+	for len(buf) > 0 {
+		size, n := binary.Uvarint(buf)
+		if n <= 0 || uint64(len(buf)-n) < size {
+			return State{}, fmt.Errorf("%w: broken field length, data: %s", ErrInvalidCallbackData, data)
+		}
+		fields = append(fields, string(buf[n:n+int(size)]))
+		buf = buf[n+int(size):]
+	}
+	st, err := stateFromFields(c.Separator, fields, data)
+	if err == nil && st.Action == "" {
+		st.Action = st.State
+	}
+	return st, err
+}
+
+func stateFromFields(separator string, fields []string, data string) (State, error) {
+	if len(fields) < 3 || len(fields) > 5 {
+		return State{}, fmt.Errorf("%w: data (%s) must has from 3 to 5 parts, but has %d",
+			ErrInvalidCallbackData, data, len(fields))
+	}
+	st := State{Separator: separator, Prefix: fields[0], State: fields[1], Action: fields[2]}
+	if len(fields) > 3 {
+		st.Key = fields[3]
+	}
+	if len(fields) > 4 {
+		st.Value = fields[4]
+	}
+	return st, nil
+}
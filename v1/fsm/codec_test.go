@@ -0,0 +1,122 @@
+package fsm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCodec_RoundTrip(t *testing.T) {
+	codecs := map[string]Codec{
+		"Plain":   PlainCodec{Separator: "_"},
+		"Escape":  EscapeCodec{Separator: "_"},
+		"Compact": CompactCodec{Separator: "_"},
+	}
+	tests := []struct {
+		name  string
+		state State
+		skip  []string
+	}{
+		{
+			name:  "Minimal",
+			state: State{Prefix: "pr", Separator: "_", State: "state1", Action: "state1"},
+		},
+		{
+			name:  "With Value",
+			state: State{Prefix: "pr", Separator: "_", State: "state1", Action: "action1", Key: "key1", Value: "value_1"},
+		},
+		{
+			name:  "Separator in Key",
+			state: State{Prefix: "pr", Separator: "_", State: "state_1", Action: "action_1", Key: `key_\1`},
+			skip:  []string{"Plain"},
+		},
+		{
+			name:  "Value without Key",
+			state: State{Prefix: "pr", Separator: "_", State: "state1", Action: "action1", Value: "value1"},
+			skip:  []string{"Plain"},
+		},
+	}
+	for _, tt := range tests {
+		for name, codec := range codecs {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				data, err := codec.Encode(tt.state)
+				for _, skip := range tt.skip {
+					if skip == name {
+						if !errors.Is(err, ErrInvalidCallbackData) {
+							t.Errorf("Codec.Encode() error = %v, wantErr %v", err, ErrInvalidCallbackData)
+						}
+						return
+					}
+				}
+				if err != nil {
+					t.Errorf("Codec.Encode() error = %v, wantErr %v", err, nil)
+					return
+				}
+				got, err := codec.Decode(data)
+				if err != nil {
+					t.Errorf("Codec.Decode() error = %v, wantErr %v", err, nil)
+					return
+				}
+				if diff := cmp.Diff(got, tt.state); diff != "" {
+					t.Errorf("Codec.Decode() difference: %v", diff)
+				}
+			})
+		}
+	}
+}
+
+func TestEscapeCodec_Encode(t *testing.T) {
+	st := State{Prefix: "pr", State: "state1", Action: "action1", Key: `a_b\c`}
+	got, err := EscapeCodec{Separator: "_"}.Encode(st)
+	if err != nil {
+		t.Errorf("EscapeCodec.Encode() error = %v, wantErr %v", err, nil)
+	}
+	if want := `pr_state1_action1_a\_b\\c`; got != want {
+		t.Errorf("EscapeCodec.Encode() = %v, want %v", got, want)
+	}
+}
+
+func TestCodec_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		codec   Codec
+		data    string
+		wantErr error
+	}{
+		{name: "Escape unknown escape", codec: EscapeCodec{Separator: "_"}, data: `pr_state1_a\b`, wantErr: ErrInvalidCallbackData},
+		{name: "Escape few parts", codec: EscapeCodec{Separator: "_"}, data: `pr_state1`, wantErr: ErrInvalidCallbackData},
+		{name: "Compact no prefix", codec: CompactCodec{Separator: "_"}, data: "pr", wantErr: ErrInvalidCallbackData},
+		{name: "Compact not base64", codec: CompactCodec{Separator: "_"}, data: "pr_***", wantErr: ErrInvalidCallbackData},
+		{name: "Compact broken length", codec: CompactCodec{Separator: "_"}, data: "pr_BQ", wantErr: ErrInvalidCallbackData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.codec.Decode(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Codec.Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestState_CallbackData(t *testing.T) {
+	st := State{Prefix: "pr", Separator: "_", State: "state1", Action: "action1", Key: "key1"}
+	got, err := st.CallbackData()
+	if err != nil {
+		t.Errorf("State.CallbackData() error = %v, wantErr %v", err, nil)
+	}
+	if want := "pr_state1_action1_key1"; got != want {
+		t.Errorf("State.CallbackData() = %v, want %v", got, want)
+	}
+
+	st.Value = strings.Repeat("v", MaxCallbackDataLength)
+	if _, err := st.CallbackData(); !errors.Is(err, ErrCallbackDataTooLong) {
+		t.Errorf("State.CallbackData() error = %v, wantErr %v", err, ErrCallbackDataTooLong)
+	}
+	for name, codec := range map[string]Codec{"Escape": EscapeCodec{Separator: "_"}, "Compact": CompactCodec{Separator: "_"}} {
+		if _, err := codec.Encode(st); !errors.Is(err, ErrCallbackDataTooLong) {
+			t.Errorf("%sCodec.Encode() error = %v, wantErr %v", name, err, ErrCallbackDataTooLong)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrDataNotFound = errors.New("the value was not found in the state data")
+)
+
+// Data
+//
+// Conversation data bag of a state, e.g. the fields of a multi-step form.
+// Values are kept as JSON, so the bag is stored by every repository together
+// with the state and cleared along with it.
+type Data map[string]json.RawMessage
+
+// Get
+//
+// Decode the value of the state data key
+func Get[T any](st State, key string) (T, error) {
+	var value T
+	raw, ok := st.Data[key]
+	if !ok {
+		return value, fmt.Errorf("%w: %s", ErrDataNotFound, key)
+	}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return value, fmt.Errorf("decode state data %s error: '%w'", key, err)
+	}
+	return value, nil
+}
+
+// Set
+//
+// Encode the value to the state data key. The data bag is copied on write,
+// so states returned by a repository are never changed in place.
+func Set[T any](st *State, key string, value T) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encode state data %s error: '%w'", key, err)
+	}
+	data := st.Data.clone(1)
+	data[key] = raw
+	st.Data = data
+	return nil
+}
+
+// Delete
+//
+// Remove the key from the state data
+func Delete(st *State, key string) {
+	if _, ok := st.Data[key]; !ok {
+		return
+	}
+	data := st.Data.clone(0)
+	delete(data, key)
+	if len(data) == 0 {
+		data = nil
+	}
+	st.Data = data
+}
+
+func (d Data) clone(extra int) Data {
+	data := make(Data, len(d)+extra)
+	for k, v := range d {
+		data[k] = v
+	}
+	return data
+}
@@ -0,0 +1,68 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testForm struct {
+	Name  string `json:"name"`
+	Phone string `json:"phone"`
+}
+
+func TestData_SetGet(t *testing.T) {
+	st := State{ChatId: "100"}
+	form := testForm{Name: "name1", Phone: "+100"}
+	if err := Set(&st, "form", form); err != nil {
+		t.Errorf("Set() error = %v, wantErr %v", err, nil)
+	}
+	if err := Set(&st, "count", 3); err != nil {
+		t.Errorf("Set() error = %v, wantErr %v", err, nil)
+	}
+
+	gotForm, err := Get[testForm](st, "form")
+	if err != nil {
+		t.Errorf("Get() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(gotForm, form); diff != "" {
+		t.Errorf("Get() difference: %v", diff)
+	}
+	gotCount, err := Get[int](st, "count")
+	if err != nil {
+		t.Errorf("Get() error = %v, wantErr %v", err, nil)
+	}
+	if gotCount != 3 {
+		t.Errorf("Get() = %v, want %v", gotCount, 3)
+	}
+
+	if _, err := Get[string](st, "absent"); !errors.Is(err, ErrDataNotFound) {
+		t.Errorf("Get() error = %v, wantErr %v", err, ErrDataNotFound)
+	}
+	if _, err := Get[int](st, "form"); err == nil {
+		t.Error("Get() wrong type must raise error")
+	}
+	if err := Set(&st, "func", func() {}); err == nil {
+		t.Error("Set() unsupported type must raise error")
+	}
+}
+
+func TestData_CopyOnWrite(t *testing.T) {
+	st := State{ChatId: "100"}
+	Set(&st, "name", "name1")
+	stored := st
+	Set(&st, "name", "name2")
+	Delete(&st, "name")
+
+	got, err := Get[string](stored, "name")
+	if err != nil {
+		t.Errorf("Get() error = %v, wantErr %v", err, nil)
+	}
+	if got != "name1" {
+		t.Errorf("Get() = %v, want %v", got, "name1")
+	}
+	if st.Data != nil {
+		t.Errorf("Delete() data = %v, want %v", st.Data, nil)
+	}
+}
@@ -0,0 +1,363 @@
+package fsm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCompactEvery int    = 1000
+	fileSnapshotName    string = "states.snapshot"
+	fileLogName         string = "states.log"
+)
+
+const (
+	fileOpSet           string = "set"
+	fileOpCompareAndSet string = "cas"
+	fileOpClear         string = "clear"
+)
+
+// fileLogEntry
+//
+// Logged operation with the repository options it was made with,
+// so the replay doesn't depend on the options of the reopened repository
+type fileLogEntry struct {
+	Seq           int64  `json:"seq"`
+	Op            string `json:"op"`
+	State         State  `json:"state"`
+	Old           *State `json:"old,omitempty"`
+	MaxChatStates int    `json:"max_chat_states,omitempty"`
+	ReplaceAll    bool   `json:"replace_all,omitempty"`
+}
+
+type fileSnapshot struct {
+	Seq    int64   `json:"seq"`
+	States []State `json:"states"`
+}
+
+// NewFileStateRepository
+//
+// Open the repository in the directory and load the states
+// from the snapshot and the operations log
+func NewFileStateRepository(dir string) (*FileStateRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	rep := &FileStateRepository{
+		CompactEvery: DefaultCompactEvery,
+		dir:          dir,
+		memory:       NewMemoryStateRepository(),
+	}
+	if err := rep.load(); err != nil {
+		return nil, err
+	}
+	if err := rep.openLog(); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// FileStateRepository
+//
+// State repository that keeps the states in memory and writes every Set and Clear
+// to an append-only JSON lines log. After CompactEvery operations the states are
+// written to a snapshot and the log is started over.
+// The states are served by an inner MemoryStateRepository with the same limits and TTL.
+// Unlike it, expired states are also left out of the snapshot, the log is replayed
+// with the MaxChatStates and ReplaceAll every operation was made with, and OnExpire
+// is called while the repository is locked, so it must not use the repository.
+type FileStateRepository struct {
+	CompactEvery  int
+	MaxChatStates int
+	ReplaceAll    bool
+	TTL           time.Duration
+	OnExpire      func(st State)
+	dir           string
+	memory        MemoryStateRepository
+	log           *os.File
+	seq           int64
+	ops           int
+	sync.Mutex
+}
+
+func (rep *FileStateRepository) Get(chatId string) ([]State, error) {
+	rep.Lock()
+	defer rep.Unlock()
+	rep.configure()
+	return rep.memory.Get(chatId)
+}
+
+func (rep *FileStateRepository) GetByMessage(chatId string, messageId int) (State, error) {
+	rep.Lock()
+	defer rep.Unlock()
+	rep.configure()
+	return rep.memory.GetByMessage(chatId, messageId)
+}
+
+func (rep *FileStateRepository) GetByKey(key string) ([]State, error) {
+	rep.Lock()
+	defer rep.Unlock()
+	rep.configure()
+	return rep.memory.GetByKey(key)
+}
+
+func (rep *FileStateRepository) Set(s State) error {
+	if s.ChatId == "" {
+		return fmt.Errorf("State ChatId can't be empty, state: %v", s)
+	}
+	if s.ExpiresAt.IsZero() && rep.TTL > 0 {
+		s = s.WithTTL(rep.TTL)
+	}
+	rep.Lock()
+	defer rep.Unlock()
+	rep.configure()
+	rep.memory.RLock()
+	s.Version = rep.memory.version(s) + 1
+	rep.memory.RUnlock()
+	return rep.write(fileLogEntry{Op: fileOpSet, State: s})
+}
+
+func (rep *FileStateRepository) CompareAndSet(old State, next State) error {
+	if next.ChatId == "" || next.ChatId != old.ChatId {
+		return fmt.Errorf("State ChatId can't be empty or changed, old state: %v, new state: %v", old, next)
+	}
+	if next.ExpiresAt.IsZero() && rep.TTL > 0 {
+		next = next.WithTTL(rep.TTL)
+	}
+
+	rep.Lock()
+	defer rep.Unlock()
+	rep.configure()
+	// The memory states are checked before the operation is logged, so conflicts are never logged
+	current, err := rep.memory.Get(old.ChatId)
+	if err != nil && !errors.Is(err, ErrStateNotFound) {
+		return err
+	}
+	version := 0
+	for _, s := range current {
+		if s.MessageId == old.MessageId && s.State == old.State {
+			version = s.Version
+		}
+	}
+	if version != old.Version {
+		return fmt.Errorf("%w: stored version %d, expected %d", ErrConflict, version, old.Version)
+	}
+	next.Version = old.Version + 1
+	return rep.write(fileLogEntry{Op: fileOpCompareAndSet, State: next, Old: &old})
+}
+
+func (rep *FileStateRepository) Clear(s State) error {
+	if s.ChatId == "" {
+		return fmt.Errorf("State ChatId can't be empty, state: %v", s)
+	}
+	rep.Lock()
+	defer rep.Unlock()
+	return rep.write(fileLogEntry{Op: fileOpClear, State: s})
+}
+
+// Compact
+//
+// Write all states to the snapshot and start the operations log over
+func (rep *FileStateRepository) Compact() error {
+	rep.Lock()
+	defer rep.Unlock()
+	return rep.compact()
+}
+
+func (rep *FileStateRepository) Close() error {
+	rep.Lock()
+	defer rep.Unlock()
+	if rep.log == nil {
+		return nil
+	}
+	err := rep.log.Close()
+	rep.log = nil
+	return err
+}
+
+func (rep *FileStateRepository) write(entry fileLogEntry) error {
+	if rep.log == nil {
+		return os.ErrClosed
+	}
+
+	entry.Seq = rep.seq + 1
+	entry.MaxChatStates = rep.MaxChatStates
+	entry.ReplaceAll = rep.ReplaceAll
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := rep.log.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", ErrUpdateState, err)
+	}
+	if err := rep.log.Sync(); err != nil {
+		return fmt.Errorf("%w: %v", ErrUpdateState, err)
+	}
+	if err := rep.apply(entry); err != nil {
+		return err
+	}
+
+	rep.ops++
+	if rep.CompactEvery > 0 && rep.ops >= rep.CompactEvery {
+		return rep.compact()
+	}
+	return nil
+}
+
+func (rep *FileStateRepository) apply(entry fileLogEntry) error {
+	rep.seq = entry.Seq
+	rep.configure()
+	rep.memory.MaxChatStates = entry.MaxChatStates
+	rep.memory.ReplaceAll = entry.ReplaceAll
+	switch entry.Op {
+	case fileOpSet:
+		// The state is logged with its version, so it is stored as is
+		rep.memory.replace(entry.State, entry.State)
+		return nil
+	case fileOpCompareAndSet:
+		if entry.Old == nil {
+			return fmt.Errorf("state log operation '%s' without old state, seq: %d", entry.Op, entry.Seq)
+		}
+		rep.memory.replace(*entry.Old, entry.State)
+		return nil
+	case fileOpClear:
+		return rep.memory.Clear(entry.State)
+	}
+	return fmt.Errorf("unknown state log operation '%s', seq: %d", entry.Op, entry.Seq)
+}
+
+// configure
+//
+// Pass the repository options to the memory states
+func (rep *FileStateRepository) configure() {
+	rep.memory.MaxChatStates = rep.MaxChatStates
+	rep.memory.ReplaceAll = rep.ReplaceAll
+	rep.memory.OnExpire = rep.OnExpire
+}
+
+func (rep *FileStateRepository) compact() error {
+	now := time.Now()
+	snapshot := fileSnapshot{Seq: rep.seq, States: []State{}}
+	for _, states := range rep.memory.chatStates {
+		for _, st := range states {
+			if !st.Expired(now) {
+				snapshot.States = append(snapshot.States, st)
+			}
+		}
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := rep.writeFile(fileSnapshotName, data); err != nil {
+		return err
+	}
+
+	if rep.log != nil {
+		if err := rep.log.Close(); err != nil {
+			return err
+		}
+		rep.log = nil
+	}
+	if err := rep.writeFile(fileLogName, nil); err != nil {
+		return err
+	}
+	rep.ops = 0
+	return rep.openLog()
+}
+
+// writeFile
+//
+// Replace the file atomically with a synced temporary file
+func (rep *FileStateRepository) writeFile(name string, data []byte) error {
+	tmp, err := os.CreateTemp(rep.dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(rep.dir, name)); err != nil {
+		return err
+	}
+	return rep.syncDir()
+}
+
+func (rep *FileStateRepository) syncDir() error {
+	dir, err := os.Open(rep.dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+func (rep *FileStateRepository) openLog() (err error) {
+	rep.log, err = os.OpenFile(filepath.Join(rep.dir, fileLogName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	return
+}
+
+func (rep *FileStateRepository) load() error {
+	data, err := os.ReadFile(filepath.Join(rep.dir, fileSnapshotName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		snapshot := fileSnapshot{}
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return fmt.Errorf("state snapshot is corrupted: %w", err)
+		}
+		for _, st := range snapshot.States {
+			rep.memory.chatStates[st.ChatId] = append(rep.memory.chatStates[st.ChatId], st)
+		}
+		rep.seq = snapshot.Seq
+	}
+
+	data, err = os.ReadFile(filepath.Join(rep.dir, fileLogName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	offset := 0
+	lines := bytes.Split(data, []byte{'\n'})
+	for i, line := range lines {
+		offset += len(line) + 1
+		if len(line) == 0 {
+			continue
+		}
+		entry := fileLogEntry{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// The last line without a line break is a write interrupted by a crash
+			if i == len(lines)-1 {
+				return os.Truncate(filepath.Join(rep.dir, fileLogName), int64(offset-len(line)-1))
+			}
+			return fmt.Errorf("state log line %d is corrupted: %w", i+1, err)
+		}
+		if entry.Seq <= rep.seq {
+			continue
+		}
+		if err := rep.apply(entry); err != nil {
+			return err
+		}
+		rep.ops++
+	}
+	return nil
+}
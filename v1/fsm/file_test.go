@@ -0,0 +1,206 @@
+package fsm
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestFileRepository(t *testing.T, dir string) *FileStateRepository {
+	t.Helper()
+	rep, err := NewFileStateRepository(dir)
+	if err != nil {
+		t.Fatalf("NewFileStateRepository() error = %v", err)
+	}
+	t.Cleanup(func() { rep.Close() })
+	return rep
+}
+
+func TestFileStateRepository_Reload(t *testing.T) {
+	tests := []struct {
+		name         string
+		compactEvery int
+		compact      bool
+	}{
+		{name: "Log only", compactEvery: 0},
+		{name: "Periodic compaction", compactEvery: 2},
+		{name: "Snapshot only", compactEvery: 0, compact: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			rep := newTestFileRepository(t, dir)
+			rep.CompactEvery = tt.compactEvery
+			for _, st := range []State{
+				{ChatId: "100", MessageId: 10, State: "state1", Key: "key1"},
+				{ChatId: "101", MessageId: 11, State: "state2", Key: "key2"},
+				{ChatId: "102", MessageId: 12, State: "state3", Key: "key1"},
+			} {
+				if err := rep.Set(st); err != nil {
+					t.Errorf("FileStateRepository.Set() error = %v, wantErr %v", err, nil)
+					return
+				}
+			}
+			if err := rep.Clear(State{ChatId: "101"}); err != nil {
+				t.Errorf("FileStateRepository.Clear() error = %v, wantErr %v", err, nil)
+				return
+			}
+			if tt.compact {
+				if err := rep.Compact(); err != nil {
+					t.Errorf("FileStateRepository.Compact() error = %v, wantErr %v", err, nil)
+					return
+				}
+			}
+			if err := rep.Close(); err != nil {
+				t.Errorf("FileStateRepository.Close() error = %v, wantErr %v", err, nil)
+			}
+
+			loaded := newTestFileRepository(t, dir)
+			if diff := cmp.Diff(loaded.memory.chatStates, rep.memory.chatStates); diff != "" {
+				t.Errorf("NewFileStateRepository() loaded states difference: %v", diff)
+			}
+			if loaded.seq != rep.seq {
+				t.Errorf("NewFileStateRepository() loaded seq = %d, want %d", loaded.seq, rep.seq)
+			}
+		})
+	}
+}
+
+func TestFileStateRepository_ReloadOptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxChatStates int
+		replaceAll    bool
+		want          []State
+	}{
+		{
+			name: "Upsert",
+			want: []State{{ChatId: "100", MessageId: 10, State: "state1", Version: 1}, {ChatId: "100", MessageId: 11, State: "state2", Version: 1}},
+		},
+		{name: "Replace all", replaceAll: true, want: []State{{ChatId: "100", MessageId: 11, State: "state2", Version: 1}}},
+		{name: "Max chat states", maxChatStates: 1, want: []State{{ChatId: "100", MessageId: 11, State: "state2", Version: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			rep := newTestFileRepository(t, dir)
+			rep.MaxChatStates = tt.maxChatStates
+			rep.ReplaceAll = tt.replaceAll
+			for _, st := range []State{{ChatId: "100", MessageId: 10, State: "state1"}, {ChatId: "100", MessageId: 11, State: "state2"}} {
+				if err := rep.Set(st); err != nil {
+					t.Errorf("FileStateRepository.Set() error = %v, wantErr %v", err, nil)
+					return
+				}
+			}
+			if err := rep.Close(); err != nil {
+				t.Errorf("FileStateRepository.Close() error = %v, wantErr %v", err, nil)
+			}
+
+			got, err := newTestFileRepository(t, dir).Get("100")
+			if err != nil {
+				t.Errorf("FileStateRepository.Get() error = %v, wantErr %v", err, nil)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("NewFileStateRepository() loaded states difference: %v", diff)
+			}
+		})
+	}
+}
+
+func TestFileStateRepository_Compact(t *testing.T) {
+	dir := t.TempDir()
+	rep := newTestFileRepository(t, dir)
+	rep.CompactEvery = 3
+	for i := 0; i < 4; i++ {
+		if err := rep.Set(State{ChatId: "100", MessageId: i, State: "state1"}); err != nil {
+			t.Errorf("FileStateRepository.Set() error = %v, wantErr %v", err, nil)
+			return
+		}
+	}
+	if rep.ops != 1 {
+		t.Errorf("FileStateRepository.Set() operations after compaction = %d, want %d", rep.ops, 1)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, fileLogName))
+	if err != nil {
+		t.Errorf("read log error = %v", err)
+	}
+	want := `{"seq":4,"op":"set","state":{"action":"","chat_id":"100","expires_at":"0001-01-01T00:00:00Z","key":"","message_id":3,"prefix":"","separator":"","state":"state1","value":"","version":1}}` + "\n"
+	if diff := cmp.Diff(string(data), want); diff != "" {
+		t.Errorf("FileStateRepository.Set() log difference: %v", diff)
+	}
+	if _, err := os.Stat(filepath.Join(dir, fileSnapshotName)); err != nil {
+		t.Errorf("FileStateRepository.Set() snapshot error = %v", err)
+	}
+}
+
+func TestFileStateRepository_TornWrite(t *testing.T) {
+	dir := t.TempDir()
+	rep := newTestFileRepository(t, dir)
+	if err := rep.Set(State{ChatId: "100", MessageId: 10, State: "state1"}); err != nil {
+		t.Errorf("FileStateRepository.Set() error = %v, wantErr %v", err, nil)
+		return
+	}
+	rep.Close()
+
+	f, err := os.OpenFile(filepath.Join(dir, fileLogName), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Errorf("open log error = %v", err)
+		return
+	}
+	f.WriteString(`{"seq":2,"op":"set","sta`)
+	f.Close()
+
+	loaded := newTestFileRepository(t, dir)
+	if err := loaded.Set(State{ChatId: "101", MessageId: 11, State: "state2"}); err != nil {
+		t.Errorf("FileStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+	loaded.Close()
+
+	reloaded := newTestFileRepository(t, dir)
+	for _, chatId := range []string{"100", "101"} {
+		if _, err := reloaded.Get(chatId); err != nil {
+			t.Errorf("FileStateRepository.Get() chat %s error = %v, wantErr %v", chatId, err, nil)
+		}
+	}
+}
+
+func TestFileStateRepository_Corrupted(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, fileLogName), []byte("corrupted\n{}\n"), 0o644)
+	if _, err := NewFileStateRepository(dir); err == nil {
+		t.Error("NewFileStateRepository() corrupted log must raise error")
+	}
+}
+
+func TestFileStateRepository_Closed(t *testing.T) {
+	rep := newTestFileRepository(t, t.TempDir())
+	rep.Close()
+	if err := rep.Set(State{ChatId: "100"}); err == nil {
+		t.Error("FileStateRepository.Set() on closed repository must raise error")
+	}
+}
+
+func TestFileStateRepository_Concurrent(t *testing.T) {
+	rep := newTestFileRepository(t, t.TempDir())
+	rep.CompactEvery = 10
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			st := State{ChatId: "100", MessageId: i, State: "state1", Key: "key1"}
+			for j := 0; j < 20; j++ {
+				rep.Set(st)
+				rep.GetByKey("key1")
+				rep.Clear(st)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if rep.seq != 8*20*2 {
+		t.Errorf("FileStateRepository seq = %d, want %d", rep.seq, 8*20*2)
+	}
+}
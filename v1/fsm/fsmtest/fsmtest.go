@@ -0,0 +1,387 @@
+// Package fsmtest is a conformance test suite for fsm.StateRepository implementations.
+package fsmtest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alex13th/telebot/v1/fsm"
+	"github.com/google/go-cmp/cmp"
+)
+
+// RepositoryFactory
+//
+// Return a new empty repository for every test
+type RepositoryFactory func(t *testing.T) fsm.StateRepository
+
+// TestStateRepository
+//
+// Run the conformance tests against repositories made by the factory
+func TestStateRepository(t *testing.T, newRepository RepositoryFactory) {
+	t.Run("Get", func(t *testing.T) { testGet(t, newRepository) })
+	t.Run("GetByMessage", func(t *testing.T) { testGetByMessage(t, newRepository) })
+	t.Run("GetByKey", func(t *testing.T) { testGetByKey(t, newRepository) })
+	t.Run("Set", func(t *testing.T) { testSet(t, newRepository) })
+	t.Run("SetSeveralStates", func(t *testing.T) { testSetSeveralStates(t, newRepository) })
+	t.Run("CompareAndSet", func(t *testing.T) { testCompareAndSet(t, newRepository) })
+	t.Run("SetVersion", func(t *testing.T) { testSetVersion(t, newRepository) })
+	t.Run("Clear", func(t *testing.T) { testClear(t, newRepository) })
+	t.Run("Data", func(t *testing.T) { testData(t, newRepository) })
+	t.Run("Expiration", func(t *testing.T) { testExpiration(t, newRepository) })
+	t.Run("Context", func(t *testing.T) { testContext(t, newRepository) })
+}
+
+func setStates(t *testing.T, rep fsm.StateRepository, states ...fsm.State) {
+	t.Helper()
+	for _, st := range states {
+		if err := rep.Set(st); err != nil {
+			t.Fatalf("StateRepository.Set() error = %v, state: %v", err, st)
+		}
+	}
+}
+
+func sortStates(states []fsm.State) {
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].ChatId != states[j].ChatId {
+			return states[i].ChatId < states[j].ChatId
+		}
+		return states[i].MessageId < states[j].MessageId
+	})
+}
+
+func testGet(t *testing.T, newRepository RepositoryFactory) {
+	rep := newRepository(t)
+	st := fsm.State{ChatId: "100", MessageId: 10, Prefix: "pr", Separator: "_", State: "state1", Action: "action1", Key: "key1", Value: "value1", Version: 1}
+	setStates(t, rep, st)
+
+	got, err := rep.Get("100")
+	if err != nil {
+		t.Errorf("StateRepository.Get() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(got, []fsm.State{st}); diff != "" {
+		t.Errorf("StateRepository.Get() difference: %v", diff)
+	}
+
+	if _, err := rep.Get("101"); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("StateRepository.Get() error = %v, wantErr %v", err, fsm.ErrStateNotFound)
+	}
+}
+
+func testGetByMessage(t *testing.T, newRepository RepositoryFactory) {
+	rep := newRepository(t)
+	st := fsm.State{ChatId: "100", MessageId: 10, State: "state1", Version: 1}
+	setStates(t, rep, st)
+
+	got, err := rep.GetByMessage("100", 10)
+	if err != nil {
+		t.Errorf("StateRepository.GetByMessage() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(got, st); diff != "" {
+		t.Errorf("StateRepository.GetByMessage() difference: %v", diff)
+	}
+
+	if _, err := rep.GetByMessage("100", 11); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("StateRepository.GetByMessage() error = %v, wantErr %v", err, fsm.ErrStateNotFound)
+	}
+	if _, err := rep.GetByMessage("101", 10); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("StateRepository.GetByMessage() error = %v, wantErr %v", err, fsm.ErrStateNotFound)
+	}
+}
+
+func testGetByKey(t *testing.T, newRepository RepositoryFactory) {
+	rep := newRepository(t)
+	states := []fsm.State{
+		{ChatId: "100", State: "state1", Key: "key1", Version: 1},
+		{ChatId: "101", MessageId: 11, State: "state2", Key: "key2", Version: 1},
+		{ChatId: "102", MessageId: 12, State: "state3", Key: "key1", Version: 1},
+	}
+	setStates(t, rep, states...)
+
+	got, err := rep.GetByKey("key1")
+	if err != nil {
+		t.Errorf("StateRepository.GetByKey() error = %v, wantErr %v", err, nil)
+	}
+	sortStates(got)
+	if diff := cmp.Diff(got, []fsm.State{states[0], states[2]}); diff != "" {
+		t.Errorf("StateRepository.GetByKey() difference: %v", diff)
+	}
+
+	if _, err := rep.GetByKey("key3"); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("StateRepository.GetByKey() error = %v, wantErr %v", err, fsm.ErrStateNotFound)
+	}
+}
+
+func testSet(t *testing.T, newRepository RepositoryFactory) {
+	rep := newRepository(t)
+	if err := rep.Set(fsm.State{State: "state1"}); err == nil {
+		t.Error("StateRepository.Set() empty ChatId must raise error")
+	}
+
+	st := fsm.State{ChatId: "100", MessageId: 10, State: "state1", Key: "key1"}
+	setStates(t, rep, st)
+	st.Key = "key2"
+	setStates(t, rep, st)
+	st.Version = 2
+
+	got, err := rep.GetByMessage("100", 10)
+	if err != nil {
+		t.Errorf("StateRepository.GetByMessage() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(got, st); diff != "" {
+		t.Errorf("StateRepository.Set() difference: %v", diff)
+	}
+	if _, err := rep.GetByKey("key1"); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("StateRepository.Set() replaced key must not be found, error = %v", err)
+	}
+}
+
+func testSetSeveralStates(t *testing.T, newRepository RepositoryFactory) {
+	rep := newRepository(t)
+	states := []fsm.State{
+		{ChatId: "100", MessageId: 10, State: "state1", Version: 1},
+		{ChatId: "100", MessageId: 11, State: "state1", Version: 1},
+		{ChatId: "100", MessageId: 10, State: "state2", Version: 1},
+	}
+	setStates(t, rep, states...)
+	updated := fsm.State{ChatId: "100", MessageId: 10, State: "state1", Key: "key1", Version: 2}
+	setStates(t, rep, updated)
+
+	got, err := rep.Get("100")
+	if err != nil {
+		t.Errorf("StateRepository.Get() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(got, []fsm.State{states[1], states[2], updated}); diff != "" {
+		t.Errorf("StateRepository.Set() several states difference: %v", diff)
+	}
+}
+
+func testCompareAndSet(t *testing.T, newRepository RepositoryFactory) {
+	rep := newRepository(t)
+	old := fsm.State{ChatId: "100", MessageId: 10, State: "state1"}
+	next := fsm.State{ChatId: "100", MessageId: 10, State: "state1", Action: "action1"}
+	if err := rep.CompareAndSet(old, next); err != nil {
+		t.Errorf("StateRepository.CompareAndSet() error = %v, wantErr %v", err, nil)
+	}
+	got, err := rep.GetByMessage("100", 10)
+	if err != nil {
+		t.Errorf("StateRepository.GetByMessage() error = %v, wantErr %v", err, nil)
+	}
+	next.Version = 1
+	if diff := cmp.Diff(got, next); diff != "" {
+		t.Errorf("StateRepository.CompareAndSet() difference: %v", diff)
+	}
+
+	if err := rep.CompareAndSet(old, next); !errors.Is(err, fsm.ErrConflict) {
+		t.Errorf("StateRepository.CompareAndSet() stale version error = %v, wantErr %v", err, fsm.ErrConflict)
+	}
+
+	moved := fsm.State{ChatId: "100", MessageId: 10, State: "state2"}
+	if err := rep.CompareAndSet(got, moved); err != nil {
+		t.Errorf("StateRepository.CompareAndSet() error = %v, wantErr %v", err, nil)
+	}
+	states, err := rep.Get("100")
+	if err != nil {
+		t.Errorf("StateRepository.Get() error = %v, wantErr %v", err, nil)
+	}
+	moved.Version = 2
+	if diff := cmp.Diff(states, []fsm.State{moved}); diff != "" {
+		t.Errorf("StateRepository.CompareAndSet() changed state difference: %v", diff)
+	}
+
+	if err := rep.CompareAndSet(fsm.State{ChatId: "100"}, fsm.State{ChatId: "101"}); err == nil {
+		t.Error("StateRepository.CompareAndSet() changed ChatId must raise error")
+	}
+}
+
+func testSetVersion(t *testing.T, newRepository RepositoryFactory) {
+	rep := newRepository(t)
+	setStates(t, rep, fsm.State{ChatId: "100", MessageId: 10, State: "state1", Version: 5})
+	read, err := rep.GetByMessage("100", 10)
+	if err != nil {
+		t.Errorf("StateRepository.GetByMessage() error = %v, wantErr %v", err, nil)
+	}
+	if read.Version != 1 {
+		t.Errorf("StateRepository.Set() version = %d, want %d", read.Version, 1)
+	}
+
+	setStates(t, rep, fsm.State{ChatId: "100", MessageId: 10, State: "state1", Action: "action1"})
+	next := read
+	next.Action = "action2"
+	if err := rep.CompareAndSet(read, next); !errors.Is(err, fsm.ErrConflict) {
+		t.Errorf("StateRepository.CompareAndSet() after Set error = %v, wantErr %v", err, fsm.ErrConflict)
+	}
+	got, err := rep.GetByMessage("100", 10)
+	if err != nil {
+		t.Errorf("StateRepository.GetByMessage() error = %v, wantErr %v", err, nil)
+	}
+	if got.Action != "action1" || got.Version != 2 {
+		t.Errorf("StateRepository.Set() = %v, want action1 with version 2", got)
+	}
+}
+
+func testClear(t *testing.T, newRepository RepositoryFactory) {
+	tests := []struct {
+		name  string
+		clear fsm.State
+		want  []fsm.State
+	}{
+		{
+			name:  "Clear message state",
+			clear: fsm.State{ChatId: "100", MessageId: 10},
+			want:  []fsm.State{{ChatId: "101", MessageId: 11, State: "state2", Version: 1}},
+		},
+		{
+			name:  "Clear state by name",
+			clear: fsm.State{ChatId: "101", State: "state2"},
+			want:  []fsm.State{{ChatId: "100", MessageId: 10, State: "state1", Version: 1}},
+		},
+		{
+			name:  "Clear all chat states",
+			clear: fsm.State{ChatId: "100"},
+			want:  []fsm.State{{ChatId: "101", MessageId: 11, State: "state2", Version: 1}},
+		},
+		{
+			name:  "Clear absent chat",
+			clear: fsm.State{ChatId: "102"},
+			want: []fsm.State{{ChatId: "100", MessageId: 10, State: "state1", Version: 1},
+				{ChatId: "101", MessageId: 11, State: "state2", Version: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep := newRepository(t)
+			setStates(t, rep,
+				fsm.State{ChatId: "100", MessageId: 10, State: "state1"},
+				fsm.State{ChatId: "101", MessageId: 11, State: "state2"})
+
+			if err := rep.Clear(tt.clear); err != nil {
+				t.Errorf("StateRepository.Clear() error = %v, wantErr %v", err, nil)
+				return
+			}
+			var got []fsm.State
+			for _, chatId := range []string{"100", "101", "102"} {
+				states, err := rep.Get(chatId)
+				if err != nil && !errors.Is(err, fsm.ErrStateNotFound) {
+					t.Errorf("StateRepository.Get() error = %v", err)
+				}
+				got = append(got, states...)
+			}
+			sortStates(got)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("StateRepository.Clear() difference: %v", diff)
+			}
+		})
+	}
+
+	rep := newRepository(t)
+	if err := rep.Clear(fsm.State{State: "state1"}); err == nil {
+		t.Error("StateRepository.Clear() empty ChatId must raise error")
+	}
+}
+
+func testData(t *testing.T, newRepository RepositoryFactory) {
+	rep := newRepository(t)
+	st := fsm.State{ChatId: "100", MessageId: 10, State: "state1", Version: 1}
+	if err := fsm.Set(&st, "name", "name1"); err != nil {
+		t.Fatalf("fsm.Set() error = %v", err)
+	}
+	if err := fsm.Set(&st, "tags", []string{"tag1", "tag2"}); err != nil {
+		t.Fatalf("fsm.Set() error = %v", err)
+	}
+	setStates(t, rep, st)
+
+	got, err := rep.GetByMessage("100", 10)
+	if err != nil {
+		t.Errorf("StateRepository.GetByMessage() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(got, st); diff != "" {
+		t.Errorf("StateRepository.Set() data difference: %v", diff)
+	}
+	tags, err := fsm.Get[[]string](got, "tags")
+	if err != nil {
+		t.Errorf("fsm.Get() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(tags, []string{"tag1", "tag2"}); diff != "" {
+		t.Errorf("fsm.Get() difference: %v", diff)
+	}
+
+	if err := rep.Clear(st); err != nil {
+		t.Errorf("StateRepository.Clear() error = %v, wantErr %v", err, nil)
+	}
+	setStates(t, rep, fsm.State{ChatId: "100", MessageId: 10, State: "state1"})
+	got, err = rep.GetByMessage("100", 10)
+	if err != nil {
+		t.Errorf("StateRepository.GetByMessage() error = %v, wantErr %v", err, nil)
+	}
+	if _, err := fsm.Get[string](got, "name"); !errors.Is(err, fsm.ErrDataNotFound) {
+		t.Errorf("fsm.Get() cleared data error = %v, wantErr %v", err, fsm.ErrDataNotFound)
+	}
+}
+
+func testExpiration(t *testing.T, newRepository RepositoryFactory) {
+	rep := newRepository(t)
+	future := time.Unix(0, time.Now().Add(time.Hour).UnixNano())
+	active := fsm.State{ChatId: "100", MessageId: 10, State: "state1", Key: "key1", ExpiresAt: future, Version: 1}
+	setStates(t, rep,
+		active,
+		fsm.State{ChatId: "100", MessageId: 11, State: "state1", Key: "key1", ExpiresAt: time.Now().Add(-time.Second)},
+		fsm.State{ChatId: "101", MessageId: 12, State: "state1", Key: "key1", ExpiresAt: time.Now().Add(-time.Second)})
+
+	got, err := rep.Get("100")
+	if err != nil {
+		t.Errorf("StateRepository.Get() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(got, []fsm.State{active}); diff != "" {
+		t.Errorf("StateRepository.Get() expired states difference: %v", diff)
+	}
+	if _, err := rep.GetByMessage("100", 11); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("StateRepository.GetByMessage() error = %v, wantErr %v", err, fsm.ErrStateNotFound)
+	}
+	if _, err := rep.Get("101"); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("StateRepository.Get() error = %v, wantErr %v", err, fsm.ErrStateNotFound)
+	}
+	got, err = rep.GetByKey("key1")
+	if err != nil {
+		t.Errorf("StateRepository.GetByKey() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(got, []fsm.State{active}); diff != "" {
+		t.Errorf("StateRepository.GetByKey() expired states difference: %v", diff)
+	}
+}
+
+func testContext(t *testing.T, newRepository RepositoryFactory) {
+	rep := fsm.WithContext(newRepository(t))
+	st := fsm.State{ChatId: "100", MessageId: 10, State: "state1", Key: "key1"}
+
+	ctx := context.Background()
+	if err := rep.SetContext(ctx, st); err != nil {
+		t.Errorf("ContextStateRepository.SetContext() error = %v, wantErr %v", err, nil)
+	}
+	if _, err := rep.GetContext(ctx, "100"); err != nil {
+		t.Errorf("ContextStateRepository.GetContext() error = %v, wantErr %v", err, nil)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := rep.SetContext(ctx, st); !errors.Is(err, context.Canceled) {
+		t.Errorf("ContextStateRepository.SetContext() error = %v, wantErr %v", err, context.Canceled)
+	}
+	if _, err := rep.GetContext(ctx, "100"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ContextStateRepository.GetContext() error = %v, wantErr %v", err, context.Canceled)
+	}
+	if _, err := rep.GetByMessageContext(ctx, "100", 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("ContextStateRepository.GetByMessageContext() error = %v, wantErr %v", err, context.Canceled)
+	}
+	if _, err := rep.GetByKeyContext(ctx, "key1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ContextStateRepository.GetByKeyContext() error = %v, wantErr %v", err, context.Canceled)
+	}
+	if err := rep.CompareAndSetContext(ctx, st, st); !errors.Is(err, context.Canceled) {
+		t.Errorf("ContextStateRepository.CompareAndSetContext() error = %v, wantErr %v", err, context.Canceled)
+	}
+	if err := rep.ClearContext(ctx, st); !errors.Is(err, context.Canceled) {
+		t.Errorf("ContextStateRepository.ClearContext() error = %v, wantErr %v", err, context.Canceled)
+	}
+}
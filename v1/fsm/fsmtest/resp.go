@@ -0,0 +1,461 @@
+package fsmtest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// RESPServer
+//
+// In-process stand-in of a Redis server for the tests. It keeps strings, hashes and sets
+// in memory and supports the commands used by fsm.RedisStateRepository, key expiration
+// and WATCH/MULTI/EXEC transactions.
+type RESPServer struct {
+	listener net.Listener
+	strings  map[string]string
+	hashes   map[string]map[string]string
+	sets     map[string]map[string]bool
+	expires  map[string]time.Time
+	versions map[string]int
+	sync.Mutex
+}
+
+// NewRESPServer
+//
+// Start the server on a local port, it is closed with the test
+func NewRESPServer(t *testing.T) *RESPServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	s := &RESPServer{
+		listener: l,
+		strings:  make(map[string]string),
+		hashes:   make(map[string]map[string]string),
+		sets:     make(map[string]map[string]bool),
+		expires:  make(map[string]time.Time),
+		versions: make(map[string]int),
+	}
+	go s.serve()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *RESPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *RESPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// respSession
+//
+// Transaction state of a connection
+type respSession struct {
+	watched map[string]int
+	multi   bool
+	queued  [][]string
+}
+
+func (s *RESPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	session := &respSession{}
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		writeReply(w, s.command(session, args))
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+type respError string
+
+func (s *RESPServer) command(session *respSession, args []string) interface{} {
+	name := strings.ToUpper(args[0])
+	s.Lock()
+	defer s.Unlock()
+	switch name {
+	case "MULTI":
+		if session.multi {
+			return respError("ERR MULTI calls can not be nested")
+		}
+		session.multi = true
+		return "OK"
+	case "DISCARD":
+		if !session.multi {
+			return respError("ERR DISCARD without MULTI")
+		}
+		*session = respSession{}
+		return "OK"
+	case "EXEC":
+		if !session.multi {
+			return respError("ERR EXEC without MULTI")
+		}
+		queued, watched := session.queued, session.watched
+		*session = respSession{}
+		for key, version := range watched {
+			s.expire(key)
+			if s.versions[key] != version {
+				return nil
+			}
+		}
+		replies := make([]interface{}, 0, len(queued))
+		for _, cmd := range queued {
+			replies = append(replies, s.exec(cmd))
+		}
+		return replies
+	case "WATCH":
+		if session.multi {
+			return respError("ERR WATCH inside MULTI is not allowed")
+		}
+		if session.watched == nil {
+			session.watched = make(map[string]int)
+		}
+		for _, key := range args[1:] {
+			s.expire(key)
+			session.watched[key] = s.versions[key]
+		}
+		return "OK"
+	case "UNWATCH":
+		if session.multi {
+			break
+		}
+		session.watched = nil
+		return "OK"
+	}
+	if session.multi {
+		session.queued = append(session.queued, args)
+		return "QUEUED"
+	}
+	return s.exec(args)
+}
+
+// exec
+//
+// Execute a data command, the server is locked
+func (s *RESPServer) exec(args []string) interface{} {
+	name := strings.ToUpper(args[0])
+	arity := map[string]int{
+		"PING": 1, "AUTH": 2, "SELECT": 2, "GET": 2, "SET": 3, "INCR": 2, "DEL": 2,
+		"HGET": 3, "HGETALL": 2, "HSET": 4, "HDEL": 3, "SADD": 3, "SREM": 3, "SMEMBERS": 2,
+		"PEXPIREAT": 3, "PERSIST": 2, "PTTL": 2, "KEYS": 2, "UNWATCH": 1,
+	}
+	n, ok := arity[name]
+	if !ok {
+		return respError(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+	if len(args) < n {
+		return respError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", args[0]))
+	}
+	if len(args) > 1 {
+		s.expire(args[1])
+	}
+	kinds := map[string]string{
+		"GET": "string", "INCR": "string", "HGET": "hash", "HGETALL": "hash", "HSET": "hash", "HDEL": "hash",
+		"SADD": "set", "SREM": "set", "SMEMBERS": "set",
+	}
+	if kind, ok := kinds[name]; ok && s.exists(args[1]) && s.kind(args[1]) != kind {
+		return respError("WRONGTYPE Operation against a key holding the wrong kind of value")
+	}
+	switch name {
+	case "PING":
+		return "PONG"
+	case "AUTH", "SELECT", "UNWATCH":
+		return "OK"
+	case "GET":
+		if v, ok := s.strings[args[1]]; ok {
+			return v
+		}
+		return nil
+	case "SET":
+		s.strings[args[1]] = args[2]
+		delete(s.expires, args[1])
+		s.touch(args[1])
+		return "OK"
+	case "INCR":
+		var v int64
+		if str, ok := s.strings[args[1]]; ok {
+			var err error
+			if v, err = strconv.ParseInt(str, 10, 64); err != nil {
+				return respError("ERR value is not an integer or out of range")
+			}
+		}
+		v++
+		s.strings[args[1]] = strconv.FormatInt(v, 10)
+		s.touch(args[1])
+		return v
+	case "DEL":
+		var count int64
+		for _, key := range args[1:] {
+			if s.exists(key) {
+				s.delete(key)
+				count++
+			}
+		}
+		return count
+	case "HGET":
+		if v, ok := s.hashes[args[1]][args[2]]; ok {
+			return v
+		}
+		return nil
+	case "HGETALL":
+		fields := make([]string, 0, len(s.hashes[args[1]]))
+		for f := range s.hashes[args[1]] {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		list := make([]interface{}, 0, 2*len(fields))
+		for _, f := range fields {
+			list = append(list, f, s.hashes[args[1]][f])
+		}
+		return list
+	case "HSET":
+		h, ok := s.hashes[args[1]]
+		if !ok {
+			h = make(map[string]string)
+			s.hashes[args[1]] = h
+		}
+		var count int64
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, ok := h[args[i]]; !ok {
+				count++
+			}
+			h[args[i]] = args[i+1]
+		}
+		s.touch(args[1])
+		return count
+	case "HDEL":
+		var count int64
+		for _, f := range args[2:] {
+			if _, ok := s.hashes[args[1]][f]; ok {
+				delete(s.hashes[args[1]], f)
+				count++
+			}
+		}
+		if len(s.hashes[args[1]]) == 0 {
+			s.delete(args[1])
+		} else if count > 0 {
+			s.touch(args[1])
+		}
+		return count
+	case "SADD":
+		set, ok := s.sets[args[1]]
+		if !ok {
+			set = make(map[string]bool)
+			s.sets[args[1]] = set
+		}
+		var count int64
+		for _, m := range args[2:] {
+			if !set[m] {
+				set[m] = true
+				count++
+			}
+		}
+		s.touch(args[1])
+		return count
+	case "SREM":
+		var count int64
+		for _, m := range args[2:] {
+			if s.sets[args[1]][m] {
+				delete(s.sets[args[1]], m)
+				count++
+			}
+		}
+		if len(s.sets[args[1]]) == 0 {
+			s.delete(args[1])
+		} else if count > 0 {
+			s.touch(args[1])
+		}
+		return count
+	case "SMEMBERS":
+		list := make([]interface{}, 0, len(s.sets[args[1]]))
+		for m := range s.sets[args[1]] {
+			list = append(list, m)
+		}
+		return list
+	case "PEXPIREAT":
+		ms, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return respError("ERR value is not an integer or out of range")
+		}
+		if !s.exists(args[1]) {
+			return int64(0)
+		}
+		s.expires[args[1]] = time.UnixMilli(ms)
+		s.touch(args[1])
+		s.expire(args[1])
+		return int64(1)
+	case "PERSIST":
+		if _, ok := s.expires[args[1]]; !ok {
+			return int64(0)
+		}
+		delete(s.expires, args[1])
+		s.touch(args[1])
+		return int64(1)
+	case "PTTL":
+		if !s.exists(args[1]) {
+			return int64(-2)
+		}
+		at, ok := s.expires[args[1]]
+		if !ok {
+			return int64(-1)
+		}
+		return time.Until(at).Milliseconds()
+	case "KEYS":
+		var keys []string
+		for key := range s.strings {
+			keys = append(keys, key)
+		}
+		for key := range s.hashes {
+			keys = append(keys, key)
+		}
+		for key := range s.sets {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		list := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			if matchPattern(args[1], key) {
+				list = append(list, key)
+			}
+		}
+		return list
+	}
+	return respError("ERR unknown command")
+}
+
+func (s *RESPServer) exists(key string) bool {
+	_, str := s.strings[key]
+	_, hash := s.hashes[key]
+	_, set := s.sets[key]
+	return str || hash || set
+}
+
+func (s *RESPServer) kind(key string) string {
+	if _, ok := s.hashes[key]; ok {
+		return "hash"
+	}
+	if _, ok := s.sets[key]; ok {
+		return "set"
+	}
+	return "string"
+}
+
+func (s *RESPServer) delete(key string) {
+	delete(s.strings, key)
+	delete(s.hashes, key)
+	delete(s.sets, key)
+	delete(s.expires, key)
+	s.touch(key)
+}
+
+// touch
+//
+// Change the version of the key, so the transactions watching it fail
+func (s *RESPServer) touch(key string) {
+	s.versions[key]++
+}
+
+func (s *RESPServer) expire(key string) {
+	if at, ok := s.expires[key]; ok && !time.Now().Before(at) {
+		s.delete(key)
+	}
+}
+
+// matchPattern
+//
+// KEYS pattern with the * wildcard only
+func matchPattern(pattern string, key string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(key, parts[0]) {
+		return false
+	}
+	key = key[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 {
+			return strings.HasSuffix(key, part)
+		}
+		j := strings.Index(key, part)
+		if j < 0 {
+			return false
+		}
+		key = key[j+len(part):]
+	}
+	return key == ""
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid command length: %s", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimRight(line, "\r\n")[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func writeReply(w *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case respError:
+		w.WriteString("-" + string(v) + "\r\n")
+	case int64:
+		w.WriteString(":" + strconv.FormatInt(v, 10) + "\r\n")
+	case string:
+		if v == "OK" || v == "QUEUED" || v == "PONG" {
+			w.WriteString("+" + v + "\r\n")
+			return
+		}
+		w.WriteString("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n")
+	case []interface{}:
+		w.WriteString("*" + strconv.Itoa(len(v)) + "\r\n")
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	}
+}
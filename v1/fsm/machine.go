@@ -0,0 +1,178 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrIllegalTransition = errors.New("the transition is not allowed")
+	ErrGuardRejected     = errors.New("the transition was rejected by the guard")
+	ErrUnknownState      = errors.New("the state is not defined in the machine")
+)
+
+// GuardFunc
+//
+// Check the transition is possible, return an error to reject it
+type GuardFunc func(ctx context.Context, from State, event string) error
+
+// CallbackFunc
+//
+// Side effect of the transition called before the new state is stored
+type CallbackFunc func(ctx context.Context, from State, to State) error
+
+// Transition
+//
+// Move the states listed in From to the state To on the Event.
+// An empty From allows the event in any state.
+type Transition struct {
+	Event    string
+	From     []string
+	To       string
+	Guard    GuardFunc
+	Callback CallbackFunc
+}
+
+func (t Transition) allowed(state string) bool {
+	if len(t.From) == 0 {
+		return true
+	}
+	for _, from := range t.From {
+		if from == state {
+			return true
+		}
+	}
+	return false
+}
+
+func NewMachine(rep StateRepository, initial string, states ...string) Machine {
+	return Machine{Initial: initial, States: append([]string{initial}, states...), repository: rep}
+}
+
+// Machine
+//
+// Declarative definition of the valid states and the transitions between them.
+// The State field of a State holds the machine state and Action holds the last fired event.
+type Machine struct {
+	Initial     string
+	States      []string
+	Transitions []Transition
+	repository  StateRepository
+}
+
+func (m *Machine) Add(t Transition) error {
+	if t.Event == "" {
+		return fmt.Errorf("transition event can't be empty, transition to: %s", t.To)
+	}
+	for _, state := range append([]string{t.To}, t.From...) {
+		if !m.defined(state) {
+			return fmt.Errorf("%w: %s", ErrUnknownState, state)
+		}
+	}
+	m.Transitions = append(m.Transitions, t)
+	return nil
+}
+
+func (m Machine) defined(state string) bool {
+	for _, s := range m.States {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func (m Machine) current(st State) string {
+	if st.State == "" {
+		return m.Initial
+	}
+	return st.State
+}
+
+func (m Machine) transition(st State, event string) (Transition, error) {
+	for _, t := range m.Transitions {
+		if t.Event == event && t.allowed(m.current(st)) {
+			return t, nil
+		}
+	}
+	return Transition{}, fmt.Errorf("%w: event '%s' in state '%s'", ErrIllegalTransition, event, m.current(st))
+}
+
+// Events
+//
+// Events that can be fired in the state
+func (m Machine) Events(st State) (events []string) {
+	seen := make(map[string]bool)
+	for _, t := range m.Transitions {
+		if !seen[t.Event] && t.allowed(m.current(st)) {
+			seen[t.Event] = true
+			events = append(events, t.Event)
+		}
+	}
+	return
+}
+
+func (m Machine) Can(st State, event string) bool {
+	_, err := m.transition(st, event)
+	return err == nil
+}
+
+// Fire
+//
+// Validate the transition for the event, call its guard and callback
+// and replace the previous state with the new one in the repository by CompareAndSet.
+// The state changed since it was read returns ErrConflict.
+func (m Machine) Fire(ctx context.Context, st State, event string) (State, error) {
+	t, err := m.transition(st, event)
+	if err != nil {
+		return st, err
+	}
+	from := st
+	from.State = m.current(st)
+	if t.Guard != nil {
+		if err := t.Guard(ctx, from, event); err != nil {
+			return st, fmt.Errorf("%w: event '%s' in state '%s': %v", ErrGuardRejected, event, from.State, err)
+		}
+	}
+	to := from
+	to.State = t.To
+	to.Action = event
+	if t.Callback != nil {
+		if err := t.Callback(ctx, from, to); err != nil {
+			return st, err
+		}
+	}
+	if m.repository != nil {
+		if err := m.repository.CompareAndSet(from, to); err != nil {
+			return st, err
+		}
+		to.Version = from.Version + 1
+	}
+	return to, nil
+}
+
+// DOT
+//
+// Graphviz diagram of the machine
+func (m Machine) DOT(name string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %q {\n", name)
+	sb.WriteString("\t\"\" [shape=point];\n")
+	fmt.Fprintf(&sb, "\t\"\" -> %q;\n", m.Initial)
+	for _, state := range m.States {
+		fmt.Fprintf(&sb, "\t%q;\n", state)
+	}
+	for _, t := range m.Transitions {
+		from := t.From
+		if len(from) == 0 {
+			from = m.States
+		}
+		for _, state := range from {
+			fmt.Fprintf(&sb, "\t%q -> %q [label=%q];\n", state, t.To, t.Event)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
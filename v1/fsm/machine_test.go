@@ -0,0 +1,192 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newOrderMachine(rep StateRepository, calls *[]string) Machine {
+	m := NewMachine(rep, "new", "paid", "shipped", "canceled")
+	m.Add(Transition{Event: "pay", From: []string{"new"}, To: "paid",
+		Callback: func(ctx context.Context, from State, to State) error {
+			*calls = append(*calls, from.State+"->"+to.State)
+			return nil
+		}})
+	m.Add(Transition{Event: "ship", From: []string{"paid"}, To: "shipped",
+		Guard: func(ctx context.Context, from State, event string) error {
+			if from.Key == "" {
+				return errors.New("address required")
+			}
+			return nil
+		}})
+	m.Add(Transition{Event: "cancel", From: []string{"new", "paid"}, To: "canceled"})
+	return m
+}
+
+func TestMachine_Add(t *testing.T) {
+	m := NewMachine(nil, "new", "done")
+	tests := []struct {
+		name    string
+		t       Transition
+		wantErr error
+	}{
+		{name: "Valid", t: Transition{Event: "finish", From: []string{"new"}, To: "done"}},
+		{name: "Any state", t: Transition{Event: "reset", To: "new"}},
+		{name: "Unknown To", t: Transition{Event: "finish", To: "unknown"}, wantErr: ErrUnknownState},
+		{name: "Unknown From", t: Transition{Event: "finish", From: []string{"unknown"}, To: "done"}, wantErr: ErrUnknownState},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.Add(tt.t); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Machine.Add() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := m.Add(Transition{To: "done"}); err == nil {
+		t.Error("Machine.Add() transition without event must raise error")
+	}
+}
+
+func TestMachine_Fire(t *testing.T) {
+	tests := []struct {
+		name      string
+		st        State
+		event     string
+		want      State
+		wantCalls []string
+		wantErr   error
+	}{
+		{
+			name:      "Initial state",
+			st:        State{ChatId: "10"},
+			event:     "pay",
+			want:      State{ChatId: "10", State: "paid", Action: "pay", Version: 1},
+			wantCalls: []string{"new->paid"},
+		},
+		{
+			name:  "Guard passed",
+			st:    State{ChatId: "10", State: "paid", Key: "address"},
+			event: "ship",
+			want:  State{ChatId: "10", State: "shipped", Action: "ship", Key: "address", Version: 1},
+		},
+		{
+			name:    "Guard rejected",
+			st:      State{ChatId: "10", State: "paid"},
+			event:   "ship",
+			want:    State{ChatId: "10", State: "paid"},
+			wantErr: ErrGuardRejected,
+		},
+		{
+			name:    "Illegal transition",
+			st:      State{ChatId: "10", State: "shipped"},
+			event:   "cancel",
+			want:    State{ChatId: "10", State: "shipped"},
+			wantErr: ErrIllegalTransition,
+		},
+		{
+			name:    "Unknown event",
+			st:      State{ChatId: "10", State: "new"},
+			event:   "refund",
+			want:    State{ChatId: "10", State: "new"},
+			wantErr: ErrIllegalTransition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			rep := NewMemoryStateRepository()
+			m := newOrderMachine(&rep, &calls)
+			got, err := m.Fire(context.Background(), tt.st, tt.event)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Machine.Fire() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Machine.Fire() difference: %v", diff)
+			}
+			if diff := cmp.Diff(calls, tt.wantCalls); diff != "" {
+				t.Errorf("Machine.Fire() callbacks difference: %v", diff)
+			}
+			stored, err := rep.Get(tt.st.ChatId)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Errorf("Machine.Fire() state stored after error: %v", stored)
+				}
+				return
+			}
+			if diff := cmp.Diff(stored, []State{tt.want}); diff != "" {
+				t.Errorf("Machine.Fire() stored state difference: %v", diff)
+			}
+		})
+	}
+}
+
+func TestMachine_Fire_Conflict(t *testing.T) {
+	var calls []string
+	rep := NewMemoryStateRepository()
+	m := newOrderMachine(&rep, &calls)
+	paid, err := m.Fire(context.Background(), State{ChatId: "10", MessageId: 1, Key: "address"}, "pay")
+	if err != nil {
+		t.Errorf("Machine.Fire() error = %v, wantErr %v", err, nil)
+		return
+	}
+	if _, err := m.Fire(context.Background(), paid, "cancel"); err != nil {
+		t.Errorf("Machine.Fire() error = %v, wantErr %v", err, nil)
+	}
+	if _, err := m.Fire(context.Background(), paid, "ship"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Machine.Fire() stale state error = %v, wantErr %v", err, ErrConflict)
+	}
+	want := []State{{ChatId: "10", MessageId: 1, State: "canceled", Action: "cancel", Key: "address", Version: 2}}
+	stored, _ := rep.Get("10")
+	if diff := cmp.Diff(stored, want); diff != "" {
+		t.Errorf("Machine.Fire() stored state difference: %v", diff)
+	}
+}
+
+func TestMachine_Fire_CallbackError(t *testing.T) {
+	cbErr := errors.New("callback error")
+	rep := NewMemoryStateRepository()
+	m := NewMachine(&rep, "new", "done")
+	m.Add(Transition{Event: "finish", To: "done",
+		Callback: func(ctx context.Context, from State, to State) error { return cbErr }})
+	if _, err := m.Fire(context.Background(), State{ChatId: "10"}, "finish"); !errors.Is(err, cbErr) {
+		t.Errorf("Machine.Fire() error = %v, wantErr %v", err, cbErr)
+	}
+	if _, err := rep.Get("10"); err == nil {
+		t.Error("Machine.Fire() state must not be stored after callback error")
+	}
+}
+
+func TestMachine_Events(t *testing.T) {
+	m := newOrderMachine(nil, &[]string{})
+	if diff := cmp.Diff(m.Events(State{}), []string{"pay", "cancel"}); diff != "" {
+		t.Errorf("Machine.Events() difference: %v", diff)
+	}
+	if m.Can(State{State: "shipped"}, "cancel") {
+		t.Error("Machine.Can() cancel must not be allowed in shipped state")
+	}
+	if !m.Can(State{State: "paid"}, "cancel") {
+		t.Error("Machine.Can() cancel must be allowed in paid state")
+	}
+}
+
+func TestMachine_DOT(t *testing.T) {
+	m := NewMachine(nil, "new", "done")
+	m.Add(Transition{Event: "finish", From: []string{"new"}, To: "done"})
+	m.Add(Transition{Event: "reset", To: "new"})
+	want := `digraph "order" {
+	"" [shape=point];
+	"" -> "new";
+	"new";
+	"done";
+	"new" -> "done" [label="finish"];
+	"new" -> "new" [label="reset"];
+	"done" -> "new" [label="reset"];
+}
+`
+	if diff := cmp.Diff(m.DOT("order"), want); diff != "" {
+		t.Errorf("Machine.DOT() difference: %v", diff)
+	}
+}
@@ -0,0 +1,149 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultNavigationDepth = 20
+	NavigationState        = "navigation"
+	navigationChatPrefix   = "navigation:"
+	navigationDataKey      = "stack"
+)
+
+// NavigationStack
+//
+// Screens a chat message went through, e.g. the levels of an inline menu.
+// The stack of a message is kept in the repository as the NavigationState state
+// of a separate chat navigation:<chatId>:<messageId>, so it neither shadows
+// the message states nor counts towards their MaxChatStates. The rows of a chat
+// have the Key navigation:<chatId>, so ClearChat finds them.
+// The stack is updated with CompareAndSet, a concurrent update returns ErrConflict,
+// and the row of an emptied stack is removed.
+type NavigationStack struct {
+	MaxDepth   int
+	repository StateRepository
+}
+
+func NewNavigationStack(rep StateRepository) NavigationStack {
+	return NavigationStack{MaxDepth: DefaultNavigationDepth, repository: rep}
+}
+
+// Push
+//
+// Put the screen on the top of the stack, the same screen on the top is replaced.
+// The bottom screens are dropped when MaxDepth is exceeded.
+func (ns NavigationStack) Push(chatId string, messageId int, st State) error {
+	row, stack, err := ns.load(chatId, messageId)
+	if err != nil {
+		return err
+	}
+	if len(stack) > 0 && stack[len(stack)-1].String() == st.String() {
+		stack = stack[:len(stack)-1]
+	}
+	stack = append(stack, st)
+	if ns.MaxDepth > 0 && len(stack) > ns.MaxDepth {
+		stack = stack[len(stack)-ns.MaxDepth:]
+	}
+	return ns.save(row, stack)
+}
+
+// Pop
+//
+// Remove the top screen from the stack and return it
+func (ns NavigationStack) Pop(chatId string, messageId int) (State, error) {
+	row, stack, err := ns.load(chatId, messageId)
+	if err != nil {
+		return State{}, err
+	}
+	if len(stack) == 0 {
+		return State{}, ErrStateNotFound
+	}
+	top := stack[len(stack)-1]
+	return top, ns.save(row, stack[:len(stack)-1])
+}
+
+// Peek
+//
+// Top screen of the stack
+func (ns NavigationStack) Peek(chatId string, messageId int) (State, error) {
+	_, stack, err := ns.load(chatId, messageId)
+	if err != nil {
+		return State{}, err
+	}
+	if len(stack) == 0 {
+		return State{}, ErrStateNotFound
+	}
+	return stack[len(stack)-1], nil
+}
+
+// Clear
+//
+// Remove all screens of the message, e.g. when the message is deleted
+func (ns NavigationStack) Clear(chatId string, messageId int) error {
+	row, stack, err := ns.load(chatId, messageId)
+	if err != nil || len(stack) == 0 {
+		return err
+	}
+	return ns.save(row, nil)
+}
+
+// ClearChat
+//
+// Remove the stacks of all messages of the chat, e.g. along with the chat states
+func (ns NavigationStack) ClearChat(chatId string) error {
+	rows, err := ns.repository.GetByKey(navigationChatPrefix + chatId)
+	if errors.Is(err, ErrStateNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := ns.repository.Clear(State{ChatId: row.ChatId}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ns NavigationStack) load(chatId string, messageId int) (State, []State, error) {
+	row := State{ChatId: navigationChatId(chatId, messageId), MessageId: messageId, State: NavigationState,
+		Key: navigationChatPrefix + chatId}
+	stored, err := ns.repository.GetByMessage(row.ChatId, messageId)
+	if err != nil && !errors.Is(err, ErrStateNotFound) {
+		return row, nil, err
+	}
+	if err == nil {
+		row = stored
+	}
+	if _, ok := row.Data[navigationDataKey]; !ok {
+		return row, nil, nil
+	}
+	stack, err := Get[[]State](row, navigationDataKey)
+	if err != nil {
+		return row, nil, fmt.Errorf("load navigation stack error: '%w'", err)
+	}
+	return row, stack, nil
+}
+
+func (ns NavigationStack) save(row State, stack []State) error {
+	next := row
+	// The repository TTL is counted from the last update
+	next.ExpiresAt = time.Time{}
+	if err := Set(&next, navigationDataKey, stack); err != nil {
+		return err
+	}
+	if err := ns.repository.CompareAndSet(row, next); err != nil || len(stack) > 0 {
+		return err
+	}
+	// The emptied stack is removed once CompareAndSet checked no concurrent update
+	return ns.repository.Clear(State{ChatId: row.ChatId})
+}
+
+func navigationChatId(chatId string, messageId int) string {
+	return navigationChatPrefix + chatId + ":" + strconv.Itoa(messageId)
+}
@@ -0,0 +1,128 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNavigationStack(t *testing.T) {
+	rep := NewMemoryStateRepository()
+	ns := NewNavigationStack(&rep)
+	ns.MaxDepth = 3
+	if _, err := ns.Peek("100", 10); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("NavigationStack.Peek() error = %v, wantErr %v", err, ErrStateNotFound)
+	}
+
+	screens := []State{
+		{Prefix: "menu", Separator: "_", State: "main"},
+		{Prefix: "menu", Separator: "_", State: "settings"},
+		{Prefix: "menu", Separator: "_", State: "settings"},
+		{Prefix: "menu", Separator: "_", State: "language"},
+		{Prefix: "menu", Separator: "_", State: "english"},
+	}
+	for _, st := range screens {
+		if err := ns.Push("100", 10, st); err != nil {
+			t.Errorf("NavigationStack.Push() error = %v, wantErr %v", err, nil)
+		}
+	}
+	if err := ns.Push("100", 11, screens[0]); err != nil {
+		t.Errorf("NavigationStack.Push() error = %v, wantErr %v", err, nil)
+	}
+
+	var got []State
+	for {
+		st, err := ns.Pop("100", 10)
+		if errors.Is(err, ErrStateNotFound) {
+			break
+		}
+		if err != nil {
+			t.Errorf("NavigationStack.Pop() error = %v, wantErr %v", err, nil)
+			return
+		}
+		got = append(got, st)
+	}
+	if diff := cmp.Diff(got, []State{screens[4], screens[3], screens[1]}); diff != "" {
+		t.Errorf("NavigationStack.Pop() difference: %v", diff)
+	}
+	if _, err := rep.Get(navigationChatId("100", 10)); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("NavigationStack.Pop() emptied stack error = %v, wantErr %v", err, ErrStateNotFound)
+	}
+
+	top, err := ns.Peek("100", 11)
+	if err != nil {
+		t.Errorf("NavigationStack.Peek() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(top, screens[0]); diff != "" {
+		t.Errorf("NavigationStack.Peek() difference: %v", diff)
+	}
+	if err := ns.Clear("100", 11); err != nil {
+		t.Errorf("NavigationStack.Clear() error = %v, wantErr %v", err, nil)
+	}
+	if _, err := ns.Peek("100", 11); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("NavigationStack.Peek() error = %v, wantErr %v", err, ErrStateNotFound)
+	}
+	if _, err := rep.Get(navigationChatId("100", 11)); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("NavigationStack.Clear() cleared stack error = %v, wantErr %v", err, ErrStateNotFound)
+	}
+}
+
+func TestNavigationStack_ClearChat(t *testing.T) {
+	rep := NewMemoryStateRepository()
+	ns := NewNavigationStack(&rep)
+	screen := State{Prefix: "menu", Separator: "_", State: "main"}
+	for _, chatId := range []string{"100", "101"} {
+		for _, messageId := range []int{10, 11} {
+			if err := ns.Push(chatId, messageId, screen); err != nil {
+				t.Errorf("NavigationStack.Push() error = %v, wantErr %v", err, nil)
+			}
+		}
+	}
+	if err := ns.ClearChat("100"); err != nil {
+		t.Errorf("NavigationStack.ClearChat() error = %v, wantErr %v", err, nil)
+	}
+	if err := ns.ClearChat("102"); err != nil {
+		t.Errorf("NavigationStack.ClearChat() error = %v, wantErr %v", err, nil)
+	}
+	for _, messageId := range []int{10, 11} {
+		if _, err := ns.Peek("100", messageId); !errors.Is(err, ErrStateNotFound) {
+			t.Errorf("NavigationStack.Peek() error = %v, wantErr %v", err, ErrStateNotFound)
+		}
+		if _, err := ns.Peek("101", messageId); err != nil {
+			t.Errorf("NavigationStack.Peek() error = %v, wantErr %v", err, nil)
+		}
+	}
+}
+
+func TestNavigationStack_MessageStates(t *testing.T) {
+	rep := NewMemoryStateRepository()
+	rep.MaxChatStates = 1
+	ns := NewNavigationStack(&rep)
+	menu := State{ChatId: "100", MessageId: 10, Prefix: "menu", Separator: "_", State: "main"}
+	if err := rep.Set(menu); err != nil {
+		t.Errorf("MemoryStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+	if err := ns.Push("100", 10, menu); err != nil {
+		t.Errorf("NavigationStack.Push() error = %v, wantErr %v", err, nil)
+	}
+	if err := rep.Set(menu); err != nil {
+		t.Errorf("MemoryStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+	got, err := rep.GetByMessage("100", 10)
+	if err != nil {
+		t.Errorf("MemoryStateRepository.GetByMessage() error = %v, wantErr %v", err, nil)
+	}
+	want := menu
+	want.Version = 2
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("MemoryStateRepository.GetByMessage() difference: %v", diff)
+	}
+	top, err := ns.Peek("100", 10)
+	if err != nil {
+		t.Errorf("NavigationStack.Peek() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(top, menu); diff != "" {
+		t.Errorf("NavigationStack.Peek() difference: %v", diff)
+	}
+}
@@ -0,0 +1,131 @@
+package fsm
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPayloadPrefix = "~"
+	DefaultPayloadTTL    = 24 * time.Hour
+	payloadChatPrefix    = "payload:"
+	payloadDataKey       = "payload"
+)
+
+// PayloadStore
+//
+// Storage of the callback payloads by their tokens
+type PayloadStore interface {
+	SavePayload(token string, st State, ttl time.Duration) error
+	LoadPayload(token string) (State, error)
+}
+
+// RepositoryPayloadStore
+//
+// Keep every payload as a separate state of the repository, so the payloads
+// expire with the repository TTL machinery
+type RepositoryPayloadStore struct {
+	Repository StateRepository
+}
+
+func (ps RepositoryPayloadStore) SavePayload(token string, st State, ttl time.Duration) error {
+	row := State{ChatId: payloadChatPrefix + token, State: payloadDataKey}
+	if ttl > 0 {
+		row = row.WithTTL(ttl)
+	}
+	if err := Set(&row, payloadDataKey, st); err != nil {
+		return err
+	}
+	return ps.Repository.Set(row)
+}
+
+func (ps RepositoryPayloadStore) LoadPayload(token string) (State, error) {
+	rows, err := ps.Repository.Get(payloadChatPrefix + token)
+	if err != nil {
+		return State{}, err
+	}
+	return Get[State](rows[0], payloadDataKey)
+}
+
+// PayloadCodec
+//
+// Encode states with the Codec and save the ones exceeding the callback data limit
+// to the Store under a short token derived from a hash of the state, so the same state
+// reuses its saved payload. The callback data of the saved state is the Prefix and
+// the token joined with the Separator.
+type PayloadCodec struct {
+	Codec     Codec
+	Store     PayloadStore
+	Prefix    string
+	Separator string
+	TTL       time.Duration
+}
+
+func NewPayloadCodec(rep StateRepository) PayloadCodec {
+	return PayloadCodec{
+		Codec:     PlainCodec{Separator: "_"},
+		Store:     RepositoryPayloadStore{Repository: rep},
+		Prefix:    DefaultPayloadPrefix,
+		Separator: "_",
+		TTL:       DefaultPayloadTTL,
+	}
+}
+
+func (c PayloadCodec) Encode(st State) (string, error) {
+	data, err := c.Codec.Encode(st)
+	if !errors.Is(err, ErrCallbackDataTooLong) {
+		return data, err
+	}
+	token, err := payloadToken(st)
+	if err != nil {
+		return "", err
+	}
+	if err := c.Store.SavePayload(token, st, c.TTL); err != nil {
+		return "", fmt.Errorf("save callback payload error: '%w'", err)
+	}
+	data = c.Prefix + c.Separator + token
+	return data, ValidateCallbackData(data)
+}
+
+func (c PayloadCodec) Decode(data string) (State, error) {
+	return c.DecodeChat("", data)
+}
+
+// DecodeChat
+//
+// Decode the data of the chat, the inline data is checked by the Codec if it is a ChatCodec
+// and the saved state for the other chat is rejected
+func (c PayloadCodec) DecodeChat(chatId string, data string) (State, error) {
+	token := strings.TrimPrefix(data, c.Prefix+c.Separator)
+	if token == data {
+		if cc, ok := c.Codec.(ChatCodec); ok {
+			return cc.DecodeChat(chatId, data)
+		}
+		return c.Codec.Decode(data)
+	}
+	st, err := c.Store.LoadPayload(token)
+	if err != nil {
+		return State{}, fmt.Errorf("load callback payload %s error: '%w'", token, err)
+	}
+	if chatId != "" && st.ChatId != "" && st.ChatId != chatId {
+		return State{}, fmt.Errorf("%w: payload %s of chat %s", ErrInvalidCallbackData, token, st.ChatId)
+	}
+	return st, nil
+}
+
+// payloadToken
+//
+// First 12 bytes of the SHA-256 hash of the state JSON in base64url, 16 characters
+func payloadToken(st State) (string, error) {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
@@ -0,0 +1,131 @@
+package fsm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPayloadCodec_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     State
+		wantToken bool
+	}{
+		{
+			name:  "Inline",
+			state: State{Prefix: "pr", Separator: "_", State: "state1", Action: "action1", Key: "key1"},
+		},
+		{
+			name:      "Oversize",
+			state:     State{Prefix: "pr", Separator: "_", State: "state1", Action: "action1", Key: "filter", Value: strings.Repeat("v", 100)},
+			wantToken: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep := NewMemoryStateRepository()
+			codec := NewPayloadCodec(&rep)
+			data, err := codec.Encode(tt.state)
+			if err != nil {
+				t.Errorf("PayloadCodec.Encode() error = %v, wantErr %v", err, nil)
+				return
+			}
+			if got := strings.HasPrefix(data, DefaultPayloadPrefix+"_"); got != tt.wantToken {
+				t.Errorf("PayloadCodec.Encode() = %v, token %v, want %v", data, got, tt.wantToken)
+			}
+			if len(data) > MaxCallbackDataLength {
+				t.Errorf("PayloadCodec.Encode() length = %d, want at most %d", len(data), MaxCallbackDataLength)
+			}
+			got, err := codec.Decode(data)
+			if err != nil {
+				t.Errorf("PayloadCodec.Decode() error = %v, wantErr %v", err, nil)
+				return
+			}
+			if diff := cmp.Diff(got, tt.state); diff != "" {
+				t.Errorf("PayloadCodec.Decode() difference: %v", diff)
+			}
+		})
+	}
+}
+
+func TestPayloadCodec_Expired(t *testing.T) {
+	rep := NewMemoryStateRepository()
+	codec := NewPayloadCodec(&rep)
+	codec.TTL = time.Millisecond
+	data, err := codec.Encode(State{Prefix: "pr", Separator: "_", State: "state1", Key: "key1", Value: strings.Repeat("v", 100)})
+	if err != nil {
+		t.Errorf("PayloadCodec.Encode() error = %v, wantErr %v", err, nil)
+		return
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, err := codec.Decode(data); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("PayloadCodec.Decode() error = %v, wantErr %v", err, ErrStateNotFound)
+	}
+	if _, err := codec.Decode(DefaultPayloadPrefix + "_unknown"); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("PayloadCodec.Decode() error = %v, wantErr %v", err, ErrStateNotFound)
+	}
+}
+
+func TestPayloadCodec_DecodeChat(t *testing.T) {
+	rep := NewMemoryStateRepository()
+	codec := NewPayloadCodec(&rep)
+	codec.Codec = NewSignedCodec(PlainCodec{Separator: "_"}, []byte("secret"))
+	inline, err := codec.Encode(State{ChatId: "100", Prefix: "pr", State: "state1", Action: "action1"})
+	if err != nil {
+		t.Errorf("PayloadCodec.Encode() error = %v, wantErr %v", err, nil)
+		return
+	}
+	saved, err := codec.Encode(State{ChatId: "100", Prefix: "pr", State: "state1", Key: "key1", Value: strings.Repeat("v", 100)})
+	if err != nil {
+		t.Errorf("PayloadCodec.Encode() error = %v, wantErr %v", err, nil)
+		return
+	}
+	tests := []struct {
+		name    string
+		chatId  string
+		data    string
+		wantErr error
+	}{
+		{name: "Inline", chatId: "100", data: inline},
+		{name: "Inline other chat", chatId: "101", data: inline, wantErr: ErrInvalidSignature},
+		{name: "Saved", chatId: "100", data: saved},
+		{name: "Saved other chat", chatId: "101", data: saved, wantErr: ErrInvalidCallbackData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := codec.DecodeChat(tt.chatId, tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("PayloadCodec.DecodeChat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPayloadCodec_Reuse(t *testing.T) {
+	rep := NewMemoryStateRepository()
+	codec := NewPayloadCodec(&rep)
+	st := State{Prefix: "pr", Separator: "_", State: "state1", Key: "filter", Value: strings.Repeat("v", 100)}
+	first, err := codec.Encode(st)
+	if err != nil {
+		t.Errorf("PayloadCodec.Encode() error = %v, wantErr %v", err, nil)
+		return
+	}
+	second, err := codec.Encode(st)
+	if err != nil {
+		t.Errorf("PayloadCodec.Encode() error = %v, wantErr %v", err, nil)
+	}
+	if first != second {
+		t.Errorf("PayloadCodec.Encode() = %v, want %v", second, first)
+	}
+	st.Value = strings.Repeat("w", 100)
+	if other, _ := codec.Encode(st); other == first {
+		t.Errorf("PayloadCodec.Encode() of other state = %v, want other token", other)
+	}
+	token := strings.TrimPrefix(first, DefaultPayloadPrefix+"_")
+	if _, err := rep.GetByKey(token); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("MemoryStateRepository.GetByKey() error = %v, wantErr %v", err, ErrStateNotFound)
+	}
+}
@@ -0,0 +1,401 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultRedisPrefix = "fsm:"
+)
+
+// NewRedisStateRepository
+//
+// Repository of the states on the Redis server of the client
+func NewRedisStateRepository(client *RedisClient) *RedisStateRepository {
+	return &RedisStateRepository{Prefix: DefaultRedisPrefix, client: client}
+}
+
+// RedisStateRepository
+//
+// State repository on a Redis server shared by several bot replicas.
+//
+// The states of a chat are kept in the hash <Prefix>chat:<chatId> with a field per
+// (MessageId, State) pair and GetByKey reads the set <Prefix>key:<key> of the chat fields
+// with the key. The chat hash expires natively with its last expiring state, a state
+// expired before the others is skipped by reads and removed by the next chat update.
+// Updates of a chat are optimistic WATCH/MULTI/EXEC transactions retried on concurrent changes,
+// an update losing several races in a row returns ErrConflict.
+//
+// MaxChatStates and ReplaceAll are applied in the update transaction.
+// There is no OnExpire, the expired states disappear without a notification.
+type RedisStateRepository struct {
+	MaxChatStates int
+	ReplaceAll    bool
+	TTL           time.Duration
+	Prefix        string
+	client        *RedisClient
+}
+
+// redisEntry
+//
+// Stored state with the sequence number of its last update, which orders the chat states
+type redisEntry struct {
+	Seq   int64 `json:"seq"`
+	State State `json:"state"`
+}
+
+func (e redisEntry) field() string {
+	return strconv.Itoa(e.State.MessageId) + ":" + e.State.State
+}
+
+func (rep *RedisStateRepository) Get(chatId string) ([]State, error) {
+	return rep.GetContext(context.Background(), chatId)
+}
+
+func (rep *RedisStateRepository) GetByMessage(chatId string, messageId int) (State, error) {
+	return rep.GetByMessageContext(context.Background(), chatId, messageId)
+}
+
+func (rep *RedisStateRepository) GetByKey(key string) ([]State, error) {
+	return rep.GetByKeyContext(context.Background(), key)
+}
+
+func (rep *RedisStateRepository) Set(s State) error {
+	return rep.SetContext(context.Background(), s)
+}
+
+func (rep *RedisStateRepository) CompareAndSet(old State, next State) error {
+	return rep.CompareAndSetContext(context.Background(), old, next)
+}
+
+func (rep *RedisStateRepository) Clear(s State) error {
+	return rep.ClearContext(context.Background(), s)
+}
+
+func (rep *RedisStateRepository) GetContext(ctx context.Context, chatId string) ([]State, error) {
+	reply, err := rep.client.Do(ctx, "HGETALL", rep.chatKey(chatId))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := redisEntries(reply)
+	if err != nil {
+		return nil, err
+	}
+	var states []State
+	for _, e := range activeRedisEntries(entries, time.Now()) {
+		states = append(states, e.State)
+	}
+	if len(states) == 0 {
+		return nil, ErrStateNotFound
+	}
+	return states, nil
+}
+
+func (rep *RedisStateRepository) GetByMessageContext(ctx context.Context, chatId string, messageId int) (State, error) {
+	states, err := rep.GetContext(ctx, chatId)
+	if err != nil {
+		return State{}, err
+	}
+	for _, s := range states {
+		if s.MessageId == messageId {
+			return s, nil
+		}
+	}
+	return State{}, ErrStateNotFound
+}
+
+func (rep *RedisStateRepository) GetByKeyContext(ctx context.Context, key string) ([]State, error) {
+	reply, err := rep.client.Do(ctx, "SMEMBERS", rep.keyKey(key))
+	if err != nil {
+		return nil, err
+	}
+	members, _ := reply.([]interface{})
+	var states []State
+	for _, m := range members {
+		member, _ := m.(string)
+		chatId, field, _ := strings.Cut(member, ":")
+		reply, err := rep.client.Do(ctx, "HGET", rep.chatKey(chatId), field)
+		if err != nil {
+			return nil, err
+		}
+		var e redisEntry
+		if reply != nil {
+			if err := json.Unmarshal([]byte(reply.(string)), &e); err != nil {
+				return nil, err
+			}
+		}
+		// The index entries of the expired chat hashes are removed lazily
+		if reply == nil || e.State.Key != key {
+			if _, err := rep.client.Do(ctx, "SREM", rep.keyKey(key), member); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !e.State.Expired(time.Now()) {
+			states = append(states, e.State)
+		}
+	}
+	if len(states) == 0 {
+		return nil, ErrStateNotFound
+	}
+	return states, nil
+}
+
+func (rep *RedisStateRepository) SetContext(ctx context.Context, s State) error {
+	if s.ChatId == "" {
+		return fmt.Errorf("State ChatId can't be empty, state: %v", s)
+	}
+	if s.ExpiresAt.IsZero() && rep.TTL > 0 {
+		s = s.WithTTL(rep.TTL)
+	}
+	return rep.update(ctx, s.ChatId, func(entries []redisEntry) ([]redisEntry, error) {
+		next := s
+		next.Version = 1
+		for _, e := range entries {
+			if e.State.MessageId == s.MessageId && e.State.State == s.State {
+				next.Version = e.State.Version + 1
+			}
+		}
+		return rep.upsert(entries, next), nil
+	})
+}
+
+// CompareAndSetContext
+//
+// Replace the old state with the next one if the stored state still has the old Version
+func (rep *RedisStateRepository) CompareAndSetContext(ctx context.Context, old State, next State) error {
+	if next.ChatId == "" || next.ChatId != old.ChatId {
+		return fmt.Errorf("State ChatId can't be empty or changed, old state: %v, next state: %v", old, next)
+	}
+	if next.ExpiresAt.IsZero() && rep.TTL > 0 {
+		next = next.WithTTL(rep.TTL)
+	}
+	next.Version = old.Version + 1
+	return rep.update(ctx, old.ChatId, func(entries []redisEntry) ([]redisEntry, error) {
+		version := 0
+		kept := make([]redisEntry, 0, len(entries))
+		for _, e := range entries {
+			if e.State.MessageId == old.MessageId && e.State.State == old.State {
+				version = e.State.Version
+				continue
+			}
+			kept = append(kept, e)
+		}
+		if version != old.Version {
+			return nil, fmt.Errorf("%w: stored version %d, expected %d", ErrConflict, version, old.Version)
+		}
+		return rep.upsert(kept, next), nil
+	})
+}
+
+func (rep *RedisStateRepository) ClearContext(ctx context.Context, s State) error {
+	if s.ChatId == "" {
+		return fmt.Errorf("State ChatId can't be empty, state: %v", s)
+	}
+	return rep.update(ctx, s.ChatId, func(entries []redisEntry) ([]redisEntry, error) {
+		if s.MessageId == 0 && s.State == "" {
+			return nil, nil
+		}
+		kept := make([]redisEntry, 0, len(entries))
+		for _, e := range entries {
+			if s.MessageId != 0 && e.State.MessageId == s.MessageId {
+				continue
+			}
+			if s.MessageId == 0 && e.State.State == s.State {
+				continue
+			}
+			kept = append(kept, e)
+		}
+		return kept, nil
+	})
+}
+
+// upsert
+//
+// Chat entries with the state added as the last one
+func (rep *RedisStateRepository) upsert(entries []redisEntry, s State) []redisEntry {
+	if rep.ReplaceAll {
+		return []redisEntry{{State: s}}
+	}
+	kept := make([]redisEntry, 0, len(entries)+1)
+	for _, e := range entries {
+		if e.State.MessageId != s.MessageId || e.State.State != s.State {
+			kept = append(kept, e)
+		}
+	}
+	kept = append(kept, redisEntry{State: s})
+	if rep.MaxChatStates > 0 && len(kept) > rep.MaxChatStates {
+		kept = kept[len(kept)-rep.MaxChatStates:]
+	}
+	return kept
+}
+
+// update
+//
+// Replace the active chat entries with the ones returned by f in a transaction.
+// New entries have zero Seq, the stored entries f doesn't return are removed.
+// After several lost races in a row ErrConflict is returned.
+func (rep *RedisStateRepository) update(ctx context.Context, chatId string,
+	f func(entries []redisEntry) ([]redisEntry, error)) error {
+	chatKey := rep.chatKey(chatId)
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		done := false
+		err := rep.client.transaction(ctx, func(conn *redisConn) error {
+			if _, err := conn.do(ctx, "WATCH", chatKey); err != nil {
+				return err
+			}
+			reply, err := conn.do(ctx, "HGETALL", chatKey)
+			if err != nil {
+				return err
+			}
+			stored, err := redisEntries(reply)
+			if err != nil {
+				return err
+			}
+			entries, err := f(activeRedisEntries(stored, time.Now()))
+			if err != nil {
+				return err
+			}
+			commands, err := rep.commands(ctx, conn, chatId, stored, entries)
+			if err != nil {
+				return err
+			}
+			if len(commands) == 0 {
+				done = true
+				_, err := conn.do(ctx, "UNWATCH")
+				return err
+			}
+			if _, err := conn.do(ctx, "MULTI"); err != nil {
+				return err
+			}
+			for _, cmd := range commands {
+				if _, err := conn.do(ctx, cmd...); err != nil {
+					return err
+				}
+			}
+			reply, err = conn.do(ctx, "EXEC")
+			if err != nil || reply == nil {
+				return err
+			}
+			// The transaction is executed, but its commands may fail one by one
+			replies, _ := reply.([]interface{})
+			for _, r := range replies {
+				if rerr, ok := r.(RedisError); ok {
+					return rerr
+				}
+			}
+			done = true
+			return nil
+		})
+		if err != nil || done {
+			return err
+		}
+		if err := retryBackoff(ctx, attempt); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: chat %s is updated concurrently", ErrConflict, chatId)
+}
+
+// commands
+//
+// Commands changing the stored chat entries to the entries
+func (rep *RedisStateRepository) commands(ctx context.Context, conn *redisConn, chatId string, stored []redisEntry, entries []redisEntry) ([][]string, error) {
+	chatKey := rep.chatKey(chatId)
+	kept := make(map[int64]bool)
+	for _, e := range entries {
+		if e.Seq != 0 {
+			kept[e.Seq] = true
+		}
+	}
+	var removes, adds [][]string
+	for _, e := range stored {
+		if kept[e.Seq] {
+			continue
+		}
+		removes = append(removes, []string{"HDEL", chatKey, e.field()})
+		if e.State.Key != "" {
+			removes = append(removes, []string{"SREM", rep.keyKey(e.State.Key), chatId + ":" + e.field()})
+		}
+	}
+
+	expiresAt := time.Time{}
+	persist := false
+	for i, e := range entries {
+		if e.Seq == 0 {
+			reply, err := conn.do(ctx, "INCR", rep.Prefix+"seq")
+			if err != nil {
+				return nil, err
+			}
+			e.Seq, _ = reply.(int64)
+			entries[i] = e
+			data, err := json.Marshal(e)
+			if err != nil {
+				return nil, err
+			}
+			adds = append(adds, []string{"HSET", chatKey, e.field(), string(data)})
+			if e.State.Key != "" {
+				adds = append(adds, []string{"SADD", rep.keyKey(e.State.Key), chatId + ":" + e.field()})
+			}
+		}
+		if e.State.ExpiresAt.IsZero() {
+			persist = true
+		} else if e.State.ExpiresAt.After(expiresAt) {
+			expiresAt = e.State.ExpiresAt
+		}
+	}
+	commands := append(removes, adds...)
+	if len(commands) == 0 || len(entries) == 0 {
+		return commands, nil
+	}
+	if persist {
+		return append(commands, []string{"PERSIST", chatKey}), nil
+	}
+	return append(commands, []string{"PEXPIREAT", chatKey, strconv.FormatInt(expiresAt.UnixMilli(), 10)}), nil
+}
+
+func (rep *RedisStateRepository) chatKey(chatId string) string {
+	return rep.Prefix + "chat:" + chatId
+}
+
+func (rep *RedisStateRepository) keyKey(key string) string {
+	return rep.Prefix + "key:" + key
+}
+
+// redisEntries
+//
+// Chat entries of the HGETALL reply in the update order
+func redisEntries(reply interface{}) ([]redisEntry, error) {
+	list, ok := reply.([]interface{})
+	if !ok && reply != nil {
+		return nil, errors.New("unexpected HGETALL reply")
+	}
+	entries := make([]redisEntry, 0, len(list)/2)
+	for i := 1; i < len(list); i += 2 {
+		data, _ := list[i].(string)
+		var e redisEntry
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	return entries, nil
+}
+
+func activeRedisEntries(entries []redisEntry, now time.Time) []redisEntry {
+	kept := make([]redisEntry, 0, len(entries))
+	for _, e := range entries {
+		if !e.State.Expired(now) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
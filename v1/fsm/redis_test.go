@@ -0,0 +1,153 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alex13th/telebot/v1/fsm"
+	"github.com/alex13th/telebot/v1/fsm/fsmtest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestRedisRepository(t *testing.T, server *fsmtest.RESPServer) (*fsm.RedisStateRepository, *fsm.RedisClient) {
+	t.Helper()
+	client := fsm.NewRedisClient(server.Addr())
+	t.Cleanup(func() { client.Close() })
+	return fsm.NewRedisStateRepository(client), client
+}
+
+func TestRedisStateRepository_TTL(t *testing.T) {
+	rep, client := newTestRedisRepository(t, fsmtest.NewRESPServer(t))
+	ctx := context.Background()
+	rep.TTL = time.Hour
+	if err := rep.Set(fsm.State{ChatId: "100", MessageId: 10, State: "state1"}); err != nil {
+		t.Errorf("RedisStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+	ttl, err := client.Do(ctx, "PTTL", "fsm:chat:100")
+	if err != nil {
+		t.Errorf("RedisClient.Do() error = %v, wantErr %v", err, nil)
+	}
+	if ms, _ := ttl.(int64); ms <= 0 || ms > time.Hour.Milliseconds() {
+		t.Errorf("RedisStateRepository.Set() chat TTL = %v ms, want up to %v ms", ttl, time.Hour.Milliseconds())
+	}
+
+	rep.TTL = 0
+	if err := rep.Set(fsm.State{ChatId: "100", MessageId: 11, State: "state1"}); err != nil {
+		t.Errorf("RedisStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+	if ttl, _ := client.Do(ctx, "PTTL", "fsm:chat:100"); ttl != int64(-1) {
+		t.Errorf("RedisStateRepository.Set() chat TTL = %v, want %v", ttl, int64(-1))
+	}
+
+	if err := rep.Set(fsm.State{ChatId: "101", State: "state1", ExpiresAt: time.Now().Add(20 * time.Millisecond)}); err != nil {
+		t.Errorf("RedisStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if exists, _ := client.Do(ctx, "PTTL", "fsm:chat:101"); exists != int64(-2) {
+		t.Errorf("RedisStateRepository.Set() expired chat PTTL = %v, want %v", exists, int64(-2))
+	}
+}
+
+func TestRedisStateRepository_KeyIndex(t *testing.T) {
+	rep, client := newTestRedisRepository(t, fsmtest.NewRESPServer(t))
+	ctx := context.Background()
+	st := fsm.State{ChatId: "100", MessageId: 10, State: "state1", Key: "key1"}
+	if err := rep.Set(st); err != nil {
+		t.Errorf("RedisStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+	st.Key = "key2"
+	if err := rep.Set(st); err != nil {
+		t.Errorf("RedisStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+	keys, err := client.Do(ctx, "KEYS", "fsm:key:*")
+	if err != nil {
+		t.Errorf("RedisClient.Do() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(keys, []interface{}{"fsm:key:key2"}); diff != "" {
+		t.Errorf("RedisStateRepository.Set() key indexes difference: %v", diff)
+	}
+
+	// The index entry of the removed chat is cleaned by the next read
+	if _, err := client.Do(ctx, "DEL", "fsm:chat:100"); err != nil {
+		t.Errorf("RedisClient.Do() error = %v, wantErr %v", err, nil)
+	}
+	if _, err := rep.GetByKey("key2"); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("RedisStateRepository.GetByKey() error = %v, wantErr %v", err, fsm.ErrStateNotFound)
+	}
+	if keys, _ := client.Do(ctx, "KEYS", "fsm:key:*"); len(keys.([]interface{})) != 0 {
+		t.Errorf("RedisStateRepository.GetByKey() stale key indexes = %v", keys)
+	}
+}
+
+func TestRedisStateRepository_Limits(t *testing.T) {
+	rep, _ := newTestRedisRepository(t, fsmtest.NewRESPServer(t))
+	rep.MaxChatStates = 2
+	for i := 0; i < 3; i++ {
+		if err := rep.Set(fsm.State{ChatId: "100", MessageId: i, State: "state1"}); err != nil {
+			t.Errorf("RedisStateRepository.Set() error = %v, wantErr %v", err, nil)
+		}
+	}
+	got, err := rep.Get("100")
+	if err != nil {
+		t.Errorf("RedisStateRepository.Get() error = %v, wantErr %v", err, nil)
+	}
+	want := []fsm.State{{ChatId: "100", MessageId: 1, State: "state1", Version: 1}, {ChatId: "100", MessageId: 2, State: "state1", Version: 1}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("RedisStateRepository.Set() limited states difference: %v", diff)
+	}
+
+	rep.ReplaceAll = true
+	if err := rep.Set(fsm.State{ChatId: "100", MessageId: 3, State: "state2"}); err != nil {
+		t.Errorf("RedisStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+	got, _ = rep.Get("100")
+	if diff := cmp.Diff(got, []fsm.State{{ChatId: "100", MessageId: 3, State: "state2", Version: 1}}); diff != "" {
+		t.Errorf("RedisStateRepository.Set() replaced states difference: %v", diff)
+	}
+}
+
+func TestRedisStateRepository_ExecError(t *testing.T) {
+	rep, client := newTestRedisRepository(t, fsmtest.NewRESPServer(t))
+	if _, err := client.Do(context.Background(), "SET", "fsm:key:key1", "value"); err != nil {
+		t.Errorf("RedisClient.Do() error = %v, wantErr %v", err, nil)
+	}
+	var rerr fsm.RedisError
+	if err := rep.Set(fsm.State{ChatId: "100", State: "state1", Key: "key1"}); !errors.As(err, &rerr) {
+		t.Errorf("RedisStateRepository.Set() error = %v, want RedisError", err)
+	}
+	if err := rep.Set(fsm.State{ChatId: "100", State: "state2"}); err != nil {
+		t.Errorf("RedisStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+}
+
+func TestRedisStateRepository_Concurrent(t *testing.T) {
+	server := fsmtest.NewRESPServer(t)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		rep, _ := newTestRedisRepository(t, server)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				st := fsm.State{ChatId: "100", MessageId: i*10 + j, State: "state1", Key: strconv.Itoa(i)}
+				if err := rep.Set(st); err != nil {
+					t.Errorf("RedisStateRepository.Set() error = %v, wantErr %v", err, nil)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	rep, _ := newTestRedisRepository(t, server)
+	got, err := rep.Get("100")
+	if err != nil {
+		t.Errorf("RedisStateRepository.Get() error = %v, wantErr %v", err, nil)
+	}
+	if len(got) != 80 {
+		t.Errorf("RedisStateRepository.Get() states = %d, want %d", len(got), 80)
+	}
+}
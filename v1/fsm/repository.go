@@ -0,0 +1,111 @@
+package fsm
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	maxUpdateAttempts = 16
+	maxUpdateBackoff  = 64 * time.Millisecond
+)
+
+// StateRepository
+//
+// Storage of the chat states. Set and CompareAndSet store the state with the version
+// following the stored one, so CompareAndSet of a state read before any update is a conflict.
+type StateRepository interface {
+	Get(chatId string) ([]State, error)
+	GetByMessage(chatId string, messageId int) (State, error)
+	GetByKey(key string) ([]State, error)
+	Set(s State) error
+	CompareAndSet(old State, next State) error
+	Clear(s State) error
+}
+
+// ContextStateRepository
+//
+// Storage of the chat states that stops the operations when the context is done
+type ContextStateRepository interface {
+	GetContext(ctx context.Context, chatId string) ([]State, error)
+	GetByMessageContext(ctx context.Context, chatId string, messageId int) (State, error)
+	GetByKeyContext(ctx context.Context, key string) ([]State, error)
+	SetContext(ctx context.Context, s State) error
+	CompareAndSetContext(ctx context.Context, old State, next State) error
+	ClearContext(ctx context.Context, s State) error
+}
+
+// WithContext
+//
+// Return the repository itself if it supports context or wrap it
+// to check the context before every operation
+func WithContext(rep StateRepository) ContextStateRepository {
+	if crep, ok := rep.(ContextStateRepository); ok {
+		return crep
+	}
+	return contextRepository{rep}
+}
+
+type contextRepository struct {
+	rep StateRepository
+}
+
+func (cr contextRepository) GetContext(ctx context.Context, chatId string) ([]State, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cr.rep.Get(chatId)
+}
+
+func (cr contextRepository) GetByMessageContext(ctx context.Context, chatId string, messageId int) (State, error) {
+	if err := ctx.Err(); err != nil {
+		return State{}, err
+	}
+	return cr.rep.GetByMessage(chatId, messageId)
+}
+
+func (cr contextRepository) GetByKeyContext(ctx context.Context, key string) ([]State, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cr.rep.GetByKey(key)
+}
+
+func (cr contextRepository) SetContext(ctx context.Context, s State) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return cr.rep.Set(s)
+}
+
+func (cr contextRepository) CompareAndSetContext(ctx context.Context, old State, next State) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return cr.rep.CompareAndSet(old, next)
+}
+
+func (cr contextRepository) ClearContext(ctx context.Context, s State) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return cr.rep.Clear(s)
+}
+
+// retryBackoff
+//
+// Wait a random exponentially growing delay before the next attempt of an update
+// that lost a race, so the retries of the concurrent updates are spread
+func retryBackoff(ctx context.Context, attempt int) error {
+	backoff := maxUpdateBackoff
+	if attempt < 16 && time.Millisecond<<attempt < maxUpdateBackoff {
+		backoff = time.Millisecond << attempt
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Duration(rand.Int63n(int64(backoff)))):
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package fsm_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/alex13th/telebot/v1/fsm"
+	"github.com/alex13th/telebot/v1/fsm/fsmtest"
+	_ "modernc.org/sqlite"
+)
+
+var (
+	_ fsm.StateRepository        = (*fsm.MemoryStateRepository)(nil)
+	_ fsm.StateRepository        = (*fsm.FileStateRepository)(nil)
+	_ fsm.StateRepository        = (*fsm.SQLStateRepository)(nil)
+	_ fsm.ContextStateRepository = (*fsm.SQLStateRepository)(nil)
+	_ fsm.StateRepository        = (*fsm.RedisStateRepository)(nil)
+	_ fsm.ContextStateRepository = (*fsm.RedisStateRepository)(nil)
+)
+
+func TestMemoryStateRepository_Conformance(t *testing.T) {
+	fsmtest.TestStateRepository(t, func(t *testing.T) fsm.StateRepository {
+		rep := fsm.NewMemoryStateRepository()
+		return &rep
+	})
+}
+
+func TestFileStateRepository_Conformance(t *testing.T) {
+	fsmtest.TestStateRepository(t, func(t *testing.T) fsm.StateRepository {
+		rep, err := fsm.NewFileStateRepository(t.TempDir())
+		if err != nil {
+			t.Fatalf("NewFileStateRepository() error = %v", err)
+		}
+		t.Cleanup(func() { rep.Close() })
+		return rep
+	})
+}
+
+func TestSQLStateRepository_Conformance(t *testing.T) {
+	fsmtest.TestStateRepository(t, func(t *testing.T) fsm.StateRepository {
+		db, err := sql.Open("sqlite", "file:"+t.TempDir()+"/states.db")
+		if err != nil {
+			t.Fatalf("sql.Open() error = %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		rep, err := fsm.NewSQLStateRepository(context.Background(), db, fsm.SQLiteDialect)
+		if err != nil {
+			t.Fatalf("NewSQLStateRepository() error = %v", err)
+		}
+		return rep
+	})
+}
+
+func TestRedisStateRepository_Conformance(t *testing.T) {
+	fsmtest.TestStateRepository(t, func(t *testing.T) fsm.StateRepository {
+		client := fsm.NewRedisClient(fsmtest.NewRESPServer(t).Addr())
+		t.Cleanup(func() { client.Close() })
+		return fsm.NewRedisStateRepository(client)
+	})
+}
@@ -0,0 +1,246 @@
+package fsm
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultRedisMaxIdle = 4
+
+// RedisError
+//
+// Error reply of the server
+type RedisError string
+
+func (e RedisError) Error() string {
+	return string(e)
+}
+
+// RedisClient
+//
+// Minimal client of the RESP (Redis) protocol with a pool of idle connections.
+// Replies are string, int64, []interface{}, nil or RedisError inside arrays.
+type RedisClient struct {
+	Network     string
+	Address     string
+	Password    string
+	DB          int
+	DialTimeout time.Duration
+	MaxIdle     int
+	idle        []*redisConn
+	closed      bool
+	sync.Mutex
+}
+
+func NewRedisClient(address string) *RedisClient {
+	return &RedisClient{Network: "tcp", Address: address, DialTimeout: 5 * time.Second, MaxIdle: DefaultRedisMaxIdle}
+}
+
+// Do
+//
+// Send the command on a pooled connection and read the reply
+func (c *RedisClient) Do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(ctx, args...)
+	c.release(conn)
+	return reply, err
+}
+
+// transaction
+//
+// Run the commands of f on one connection, e.g. WATCH, MULTI and EXEC.
+// The WATCH or MULTI state left by a failed f is reset before the connection is pooled,
+// a connection broken by an error other than an error reply may have an unread reply and is closed.
+func (c *RedisClient) transaction(ctx context.Context, f func(conn *redisConn) error) error {
+	conn, err := c.conn(ctx)
+	if err != nil {
+		return err
+	}
+	err = f(conn)
+	if err != nil && !conn.broken {
+		reset := "UNWATCH"
+		if conn.multi {
+			reset = "DISCARD"
+		}
+		if _, rerr := conn.do(ctx, reset); rerr != nil {
+			conn.broken = true
+		}
+	}
+	c.release(conn)
+	return err
+}
+
+// Close
+//
+// Close the idle connections, connections in use are closed when they are released
+func (c *RedisClient) Close() error {
+	c.Lock()
+	defer c.Unlock()
+	c.closed = true
+	var err error
+	for _, conn := range c.idle {
+		if cerr := conn.Close(); cerr != nil {
+			err = cerr
+		}
+	}
+	c.idle = nil
+	return err
+}
+
+func (c *RedisClient) conn(ctx context.Context) (*redisConn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.Lock()
+	if c.closed {
+		c.Unlock()
+		return nil, errors.New("redis client is closed")
+	}
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.Unlock()
+		return conn, nil
+	}
+	c.Unlock()
+
+	dialer := net.Dialer{Timeout: c.DialTimeout}
+	nc, err := dialer.DialContext(ctx, c.Network, c.Address)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: nc, reader: bufio.NewReader(nc)}
+	if c.Password != "" {
+		if _, err := conn.do(ctx, "AUTH", c.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.DB != 0 {
+		if _, err := conn.do(ctx, "SELECT", strconv.Itoa(c.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// release
+//
+// Return the connection to the pool, a broken connection is closed.
+// Error replies of the server don't break the connection.
+func (c *RedisClient) release(conn *redisConn) {
+	if conn.broken {
+		conn.Close()
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	if c.closed || len(c.idle) >= c.MaxIdle {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+	multi  bool
+	broken bool
+}
+
+// do
+//
+// Send the command and read the reply, the connection is marked broken
+// by any error except an error reply of the server
+func (conn *redisConn) do(ctx context.Context, args ...string) (interface{}, error) {
+	reply, err := conn.roundTrip(ctx, args...)
+	if err != nil {
+		conn.broken = true
+		return nil, err
+	}
+	switch strings.ToUpper(args[0]) {
+	case "MULTI":
+		conn.multi = true
+	case "EXEC", "DISCARD":
+		conn.multi = false
+	}
+	if rerr, ok := reply.(RedisError); ok {
+		return nil, rerr
+	}
+	return reply, nil
+}
+
+func (conn *redisConn) roundTrip(ctx context.Context, args ...string) (interface{}, error) {
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return readRESP(conn.reader)
+}
+
+// readRESP
+//
+// Read a RESP2 value
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("invalid RESP line: %q", line)
+	}
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return RedisError(line), nil
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(line)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			if list[i], err = readRESP(r); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("invalid RESP type: %q", kind)
+}
@@ -0,0 +1,49 @@
+package fsm
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadRESP(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "Simple string", data: "+OK\r\n", want: "OK"},
+		{name: "Error", data: "-ERR wrong\r\n", want: RedisError("ERR wrong")},
+		{name: "Integer", data: ":42\r\n", want: int64(42)},
+		{name: "Bulk string", data: "$5\r\na\r\nbc\r\n", want: "a\r\nbc"},
+		{name: "Nil", data: "$-1\r\n", want: nil},
+		{name: "Array", data: "*2\r\n$1\r\na\r\n:1\r\n", want: []interface{}{"a", int64(1)}},
+		{name: "Nil array", data: "*-1\r\n", want: nil},
+		{name: "Unknown type", data: "?1\r\n", wantErr: true},
+		{name: "Broken line", data: "+OK\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readRESP(bufio.NewReader(strings.NewReader(tt.data)))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readRESP() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("readRESP() difference: %v", diff)
+			}
+		})
+	}
+}
+
+func TestRedisClient_Closed(t *testing.T) {
+	client := NewRedisClient("127.0.0.1:0")
+	client.Close()
+	if _, err := client.Do(context.Background(), "PING"); err == nil {
+		t.Error("RedisClient.Do() on closed client must raise error")
+	}
+}
@@ -0,0 +1,130 @@
+package fsm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTagSize      = 8
+	DefaultSignedMaxAge = 24 * time.Hour
+)
+
+var (
+	ErrInvalidSignature    = errors.New("the callback data signature is invalid")
+	ErrCallbackDataExpired = errors.New("the callback data is expired")
+)
+
+// ChatCodec
+//
+// Codec that checks the callback data belongs to the chat it came from
+type ChatCodec interface {
+	Codec
+	DecodeChat(chatId string, data string) (State, error)
+}
+
+// SignedCodec
+//
+// Append a truncated HMAC-SHA256 tag of the data encoded by the Codec, so the
+// callback data can't be forged without the Secret. A state with ChatId is signed
+// for that chat only and with MaxAge the data is signed with its expiration time,
+// so it can't be replayed in other chats or later. Data signed with zero MaxAge never expires,
+// NewSignedCodec sets DefaultSignedMaxAge. Within MaxAge the data of a chat is still
+// accepted repeatedly, e.g. from an old message of the chat.
+//
+// The signed form is the data, the base36 expiration time if MaxAge is set and
+// the base64url tag of TagSize bytes joined with the Separator.
+type SignedCodec struct {
+	Codec     Codec
+	Secret    []byte
+	Separator string
+	TagSize   int
+	MaxAge    time.Duration
+	now       func() time.Time
+}
+
+func NewSignedCodec(c Codec, secret []byte) SignedCodec {
+	return SignedCodec{Codec: c, Secret: secret, Separator: "_", TagSize: DefaultTagSize, MaxAge: DefaultSignedMaxAge}
+}
+
+func (c SignedCodec) Encode(st State) (string, error) {
+	if len(c.Secret) == 0 {
+		return "", fmt.Errorf("%w: empty secret", ErrInvalidSignature)
+	}
+	data, err := c.Codec.Encode(st)
+	if err != nil {
+		return "", err
+	}
+	if c.MaxAge > 0 {
+		data += c.Separator + strconv.FormatInt(c.time().Add(c.MaxAge).Unix(), 36)
+	}
+	data += c.Separator + c.tag(st.ChatId, data)
+	return data, ValidateCallbackData(data)
+}
+
+// Decode
+//
+// Verify the data signed without a chat and decode it
+func (c SignedCodec) Decode(data string) (State, error) {
+	return c.DecodeChat("", data)
+}
+
+// DecodeChat
+//
+// Verify the data signed for the chat or without a chat and decode it
+func (c SignedCodec) DecodeChat(chatId string, data string) (State, error) {
+	// The tag has the fixed length and can contain the separator
+	i := len(data) - base64.RawURLEncoding.EncodedLen(c.tagSize()) - len(c.Separator)
+	if i < 0 || !strings.HasPrefix(data[i:], c.Separator) || len(c.Secret) == 0 {
+		return State{}, fmt.Errorf("%w: data: %s", ErrInvalidSignature, data)
+	}
+	signed, tag := data[:i], data[i+len(c.Separator):]
+	if !hmac.Equal([]byte(tag), []byte(c.tag(chatId, signed))) &&
+		(chatId == "" || !hmac.Equal([]byte(tag), []byte(c.tag("", signed)))) {
+		return State{}, fmt.Errorf("%w: data: %s", ErrInvalidSignature, data)
+	}
+
+	if c.MaxAge > 0 {
+		i := strings.LastIndex(signed, c.Separator)
+		if i < 0 {
+			return State{}, fmt.Errorf("%w: no expiration, data: %s", ErrInvalidCallbackData, data)
+		}
+		expires, err := strconv.ParseInt(signed[i+len(c.Separator):], 36, 64)
+		if err != nil {
+			return State{}, fmt.Errorf("%w: %v, data: %s", ErrInvalidCallbackData, err, data)
+		}
+		if !c.time().Before(time.Unix(expires, 0)) {
+			return State{}, fmt.Errorf("%w: data: %s", ErrCallbackDataExpired, data)
+		}
+		signed = signed[:i]
+	}
+	return c.Codec.Decode(signed)
+}
+
+func (c SignedCodec) tag(chatId string, data string) string {
+	mac := hmac.New(sha256.New, c.Secret)
+	mac.Write([]byte(chatId))
+	mac.Write([]byte{0})
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:c.tagSize()])
+}
+
+func (c SignedCodec) tagSize() int {
+	if c.TagSize <= 0 || c.TagSize > sha256.Size {
+		return DefaultTagSize
+	}
+	return c.TagSize
+}
+
+func (c SignedCodec) time() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,98 @@
+package fsm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSignedCodec_RoundTrip(t *testing.T) {
+	st := State{Prefix: "order", Separator: "_", State: "list", Action: "pay", Key: "1001"}
+	for _, maxAge := range []time.Duration{0, time.Hour} {
+		codec := NewSignedCodec(PlainCodec{Separator: "_"}, []byte("secret"))
+		codec.MaxAge = maxAge
+		data, err := codec.Encode(st)
+		if err != nil {
+			t.Errorf("SignedCodec.Encode() error = %v, wantErr %v", err, nil)
+			continue
+		}
+		got, err := codec.Decode(data)
+		if err != nil {
+			t.Errorf("SignedCodec.Decode() error = %v, wantErr %v", err, nil)
+			continue
+		}
+		if diff := cmp.Diff(got, st); diff != "" {
+			t.Errorf("SignedCodec.Decode() difference: %v", diff)
+		}
+	}
+}
+
+func TestSignedCodec_Rejected(t *testing.T) {
+	now := time.Now()
+	codec := NewSignedCodec(PlainCodec{Separator: "_"}, []byte("secret"))
+	codec.MaxAge = time.Minute
+	codec.now = func() time.Time { return now }
+	data, err := codec.Encode(State{ChatId: "100", Prefix: "order", State: "list", Action: "pay", Key: "1001"})
+	if err != nil {
+		t.Errorf("SignedCodec.Encode() error = %v, wantErr %v", err, nil)
+		return
+	}
+	if _, err := codec.DecodeChat("100", data); err != nil {
+		t.Errorf("SignedCodec.DecodeChat() error = %v, wantErr %v", err, nil)
+	}
+
+	other := NewSignedCodec(PlainCodec{Separator: "_"}, []byte("other"))
+	other.MaxAge = time.Minute
+	expired := codec
+	expired.now = func() time.Time { return now.Add(time.Minute) }
+	tests := []struct {
+		name    string
+		codec   SignedCodec
+		chatId  string
+		data    string
+		wantErr error
+	}{
+		{name: "Forged key", codec: codec, chatId: "100", data: strings.Replace(data, "1001", "1002", 1), wantErr: ErrInvalidSignature},
+		{name: "Other chat", codec: codec, chatId: "101", data: data, wantErr: ErrInvalidSignature},
+		{name: "Without chat", codec: codec, data: data, wantErr: ErrInvalidSignature},
+		{name: "Other secret", codec: other, chatId: "100", data: data, wantErr: ErrInvalidSignature},
+		{name: "Expired", codec: expired, chatId: "100", data: data, wantErr: ErrCallbackDataExpired},
+		{name: "Unsigned", codec: codec, chatId: "100", data: "order_list_pay_1001", wantErr: ErrInvalidSignature},
+		{name: "Short", codec: codec, chatId: "100", data: "order", wantErr: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.codec.DecodeChat(tt.chatId, tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SignedCodec.DecodeChat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignedCodec_DefaultMaxAge(t *testing.T) {
+	now := time.Now()
+	codec := NewSignedCodec(PlainCodec{Separator: "_"}, []byte("secret"))
+	codec.now = func() time.Time { return now }
+	data, err := codec.Encode(State{ChatId: "100", Prefix: "order", State: "list", Action: "pay"})
+	if err != nil {
+		t.Errorf("SignedCodec.Encode() error = %v, wantErr %v", err, nil)
+		return
+	}
+	codec.now = func() time.Time { return now.Add(DefaultSignedMaxAge) }
+	if _, err := codec.DecodeChat("100", data); !errors.Is(err, ErrCallbackDataExpired) {
+		t.Errorf("SignedCodec.DecodeChat() error = %v, wantErr %v", err, ErrCallbackDataExpired)
+	}
+}
+
+func TestSignedCodec_Limit(t *testing.T) {
+	codec := NewSignedCodec(PlainCodec{Separator: "_"}, []byte("secret"))
+	if _, err := codec.Encode(State{Prefix: "pr", State: "state1", Key: strings.Repeat("k", 50)}); !errors.Is(err, ErrCallbackDataTooLong) {
+		t.Errorf("SignedCodec.Encode() error = %v, wantErr %v", err, ErrCallbackDataTooLong)
+	}
+	if _, err := (SignedCodec{Codec: PlainCodec{Separator: "_"}}).Encode(State{Prefix: "pr", State: "state1"}); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("SignedCodec.Encode() empty secret error = %v, wantErr %v", err, ErrInvalidSignature)
+	}
+}
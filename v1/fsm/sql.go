@@ -0,0 +1,532 @@
+package fsm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultSQLTable string = "fsm_states"
+
+// SQLDialect
+//
+// Differences of the databases used by SQLStateRepository.
+// UniqueViolation reports the error of an insert violating the unique state index,
+// which is how a concurrent insert of the same state shows up.
+type SQLDialect struct {
+	Placeholder     func(n int) string
+	IdColumn        string
+	UniqueViolation func(err error) bool
+}
+
+var (
+	PostgresDialect = SQLDialect{
+		Placeholder:     func(n int) string { return "$" + strconv.Itoa(n) },
+		IdColumn:        "id BIGSERIAL PRIMARY KEY",
+		UniqueViolation: postgresUniqueViolation,
+	}
+	SQLiteDialect = SQLDialect{
+		Placeholder: func(n int) string { return "?" },
+		IdColumn:    "id INTEGER PRIMARY KEY AUTOINCREMENT",
+		UniqueViolation: func(err error) bool {
+			return strings.Contains(err.Error(), "UNIQUE constraint failed")
+		},
+	}
+)
+
+// postgresUniqueViolation
+//
+// The error has the unique_violation SQLSTATE of the lib/pq and pgx drivers
+func postgresUniqueViolation(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		return pgErr.SQLState() == "23505"
+	}
+	return strings.Contains(err.Error(), "duplicate key value violates unique constraint")
+}
+
+// sqlMigrations
+//
+// Schema versions of the states table, every migration is applied once in a transaction
+var sqlMigrations = []func(d SQLDialect, table string) []string{
+	func(d SQLDialect, table string) []string {
+		return []string{
+			fmt.Sprintf(`CREATE TABLE %s (
+				%s,
+				chat_id TEXT NOT NULL,
+				message_id INTEGER NOT NULL DEFAULT 0,
+				prefix TEXT NOT NULL DEFAULT '',
+				separator TEXT NOT NULL DEFAULT '',
+				state TEXT NOT NULL DEFAULT '',
+				action TEXT NOT NULL DEFAULT '',
+				state_key TEXT NOT NULL DEFAULT '',
+				state_value TEXT NOT NULL DEFAULT ''
+			)`, table, d.IdColumn),
+			fmt.Sprintf("CREATE INDEX %[1]s_chat_message_idx ON %[1]s (chat_id, message_id)", table),
+			fmt.Sprintf("CREATE INDEX %[1]s_key_idx ON %[1]s (state_key)", table),
+		}
+	},
+	func(d SQLDialect, table string) []string {
+		return []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN expires_at BIGINT NOT NULL DEFAULT 0", table),
+			fmt.Sprintf("CREATE INDEX %[1]s_expires_idx ON %[1]s (expires_at)", table),
+		}
+	},
+	func(d SQLDialect, table string) []string {
+		return []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN version INTEGER NOT NULL DEFAULT 0", table),
+			fmt.Sprintf("CREATE UNIQUE INDEX %[1]s_state_idx ON %[1]s (chat_id, message_id, state)", table),
+		}
+	},
+	func(d SQLDialect, table string) []string {
+		return []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN state_data TEXT NOT NULL DEFAULT ''", table),
+		}
+	},
+}
+
+// NewSQLStateRepository
+//
+// Create the repository in the database and migrate the states table to the last schema version
+func NewSQLStateRepository(ctx context.Context, db *sql.DB, dialect SQLDialect) (*SQLStateRepository, error) {
+	return NewSQLStateRepositoryTable(ctx, db, dialect, DefaultSQLTable)
+}
+
+// NewSQLStateRepositoryTable
+//
+// Create the repository in the table, e.g. for several bots in one database.
+// The table name is a plain SQL identifier, the schema versions are kept in <table>_migrations.
+func NewSQLStateRepositoryTable(ctx context.Context, db *sql.DB, dialect SQLDialect, table string) (*SQLStateRepository, error) {
+	if !sqlIdentifier(table) {
+		return nil, fmt.Errorf("invalid states table name: %q", table)
+	}
+	rep := &SQLStateRepository{db: db, dialect: dialect, table: table}
+	if err := rep.migrate(ctx); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// SQLStateRepository
+//
+// State repository in a database/sql database, a row per state.
+// MaxChatStates and ReplaceAll are applied in the Set transaction.
+// Expired rows stay in the table until the Sweep removes them, reads skip them
+// unless OnExpire is set, then a read removes the expired rows it finds.
+// OnExpire is called once for every removed row, even if several replicas see it expired.
+// StartSweeper passes the Sweep errors to OnSweepError.
+// Set retries when a concurrent Set inserts the same state first
+// and returns ErrConflict after losing several races in a row,
+// CompareAndSet returns ErrConflict at once.
+type SQLStateRepository struct {
+	MaxChatStates int
+	ReplaceAll    bool
+	TTL           time.Duration
+	OnExpire      func(st State)
+	OnSweepError  func(err error)
+	db            *sql.DB
+	dialect       SQLDialect
+	table         string
+}
+
+const sqlStateColumns = "chat_id, message_id, prefix, separator, state, action, state_key, state_value, expires_at, version, state_data"
+
+func (rep *SQLStateRepository) Get(chatId string) ([]State, error) {
+	return rep.GetContext(context.Background(), chatId)
+}
+
+func (rep *SQLStateRepository) GetByMessage(chatId string, messageId int) (State, error) {
+	return rep.GetByMessageContext(context.Background(), chatId, messageId)
+}
+
+func (rep *SQLStateRepository) GetByKey(key string) ([]State, error) {
+	return rep.GetByKeyContext(context.Background(), key)
+}
+
+func (rep *SQLStateRepository) Set(s State) error {
+	return rep.SetContext(context.Background(), s)
+}
+
+func (rep *SQLStateRepository) Clear(s State) error {
+	return rep.ClearContext(context.Background(), s)
+}
+
+func (rep *SQLStateRepository) GetContext(ctx context.Context, chatId string) ([]State, error) {
+	return rep.query(ctx, "chat_id = "+rep.dialect.Placeholder(1), chatId)
+}
+
+func (rep *SQLStateRepository) GetByMessageContext(ctx context.Context, chatId string, messageId int) (State, error) {
+	states, err := rep.query(ctx,
+		fmt.Sprintf("chat_id = %s AND message_id = %s", rep.dialect.Placeholder(1), rep.dialect.Placeholder(2)),
+		chatId, messageId)
+	if err != nil {
+		return State{}, err
+	}
+	return states[0], nil
+}
+
+func (rep *SQLStateRepository) GetByKeyContext(ctx context.Context, key string) ([]State, error) {
+	return rep.query(ctx, "state_key = "+rep.dialect.Placeholder(1), key)
+}
+
+func (rep *SQLStateRepository) SetContext(ctx context.Context, s State) error {
+	if s.ChatId == "" {
+		return fmt.Errorf("State ChatId can't be empty, state: %v", s)
+	}
+	if s.ExpiresAt.IsZero() && rep.TTL > 0 {
+		s = s.WithTTL(rep.TTL)
+	}
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		err := rep.transaction(ctx, func(tx *sql.Tx) error {
+			var version int
+			err := tx.QueryRowContext(ctx,
+				fmt.Sprintf("SELECT version FROM %s WHERE chat_id = %s AND message_id = %s AND state = %s "+
+					"AND (expires_at = 0 OR expires_at > %s)", rep.table, rep.dialect.Placeholder(1),
+					rep.dialect.Placeholder(2), rep.dialect.Placeholder(3), rep.dialect.Placeholder(4)),
+				s.ChatId, s.MessageId, s.State, sqlTime(time.Now())).Scan(&version)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			next := s
+			next.Version = version + 1
+			return rep.insert(ctx, tx, next)
+		})
+		// The concurrently inserted state is committed, so the next attempt replaces it
+		if !rep.uniqueViolation(err) {
+			return err
+		}
+		if err := retryBackoff(ctx, attempt); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: state %s of chat %s is set concurrently", ErrConflict, s.State, s.ChatId)
+}
+
+// CompareAndSet
+//
+// Replace the old state with the next one if the stored state still has the old Version
+func (rep *SQLStateRepository) CompareAndSet(old State, next State) error {
+	return rep.CompareAndSetContext(context.Background(), old, next)
+}
+
+func (rep *SQLStateRepository) CompareAndSetContext(ctx context.Context, old State, next State) error {
+	if next.ChatId == "" || next.ChatId != old.ChatId {
+		return fmt.Errorf("State ChatId can't be empty or changed, old state: %v, next state: %v", old, next)
+	}
+	if next.ExpiresAt.IsZero() && rep.TTL > 0 {
+		next = next.WithTTL(rep.TTL)
+	}
+	next.Version = old.Version + 1
+
+	key := fmt.Sprintf("chat_id = %s AND message_id = %s AND state = %s",
+		rep.dialect.Placeholder(1), rep.dialect.Placeholder(2), rep.dialect.Placeholder(3))
+	now := sqlTime(time.Now())
+	err := rep.transaction(ctx, func(tx *sql.Tx) error {
+		// Expired state is absent and doesn't conflict with zero version
+		_, err := tx.ExecContext(ctx,
+			fmt.Sprintf("DELETE FROM %s WHERE %s AND expires_at <> 0 AND expires_at <= %s",
+				rep.table, key, rep.dialect.Placeholder(4)),
+			old.ChatId, old.MessageId, old.State, now)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx,
+			fmt.Sprintf("DELETE FROM %s WHERE %s AND version = %s", rep.table, key, rep.dialect.Placeholder(4)),
+			old.ChatId, old.MessageId, old.State, old.Version)
+		if err != nil {
+			return err
+		}
+		removed, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			var count int
+			err = tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", rep.table, key),
+				old.ChatId, old.MessageId, old.State).Scan(&count)
+			if err != nil {
+				return err
+			}
+			if count > 0 || old.Version != 0 {
+				return fmt.Errorf("%w: expected version %d", ErrConflict, old.Version)
+			}
+		}
+		return rep.insert(ctx, tx, next)
+	})
+	if rep.uniqueViolation(err) {
+		return fmt.Errorf("%w: expected version %d, the state was inserted concurrently", ErrConflict, old.Version)
+	}
+	return err
+}
+
+// insert
+//
+// Replace the state with the same MessageId and State or all chat states in ReplaceAll mode
+// and evict the oldest states of the chat
+func (rep *SQLStateRepository) insert(ctx context.Context, tx *sql.Tx, s State) error {
+	where := "chat_id = " + rep.dialect.Placeholder(1)
+	args := []interface{}{s.ChatId}
+	if !rep.ReplaceAll {
+		where += fmt.Sprintf(" AND message_id = %s AND state = %s", rep.dialect.Placeholder(2), rep.dialect.Placeholder(3))
+		args = append(args, s.MessageId, s.State)
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", rep.table, where), args...)
+	if err != nil {
+		return err
+	}
+	data, err := sqlData(s.Data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", rep.table, sqlStateColumns, rep.placeholders(11)),
+		s.ChatId, s.MessageId, s.Prefix, s.Separator, s.State, s.Action, s.Key, s.Value, sqlTime(s.ExpiresAt), s.Version, data)
+	if err != nil || rep.ReplaceAll || rep.MaxChatStates <= 0 {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %[1]s WHERE chat_id = %[2]s AND id NOT IN "+
+			"(SELECT id FROM %[1]s WHERE chat_id = %[3]s ORDER BY id DESC LIMIT %[4]d)",
+			rep.table, rep.dialect.Placeholder(1), rep.dialect.Placeholder(2), rep.MaxChatStates),
+		s.ChatId, s.ChatId)
+	return err
+}
+
+func (rep *SQLStateRepository) ClearContext(ctx context.Context, s State) error {
+	if s.ChatId == "" {
+		return fmt.Errorf("State ChatId can't be empty, state: %v", s)
+	}
+	where := "chat_id = " + rep.dialect.Placeholder(1)
+	args := []interface{}{s.ChatId}
+	if s.MessageId != 0 {
+		where += " AND message_id = " + rep.dialect.Placeholder(2)
+		args = append(args, s.MessageId)
+	} else if s.State != "" {
+		where += " AND state = " + rep.dialect.Placeholder(2)
+		args = append(args, s.State)
+	}
+	return rep.transaction(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", rep.table, where), args...)
+		return err
+	})
+}
+
+// Sweep
+//
+// Remove expired states, call OnExpire for them and return the number of removed states
+func (rep *SQLStateRepository) Sweep(ctx context.Context) (int, error) {
+	rows, err := rep.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE expires_at <> 0 AND expires_at <= %s ORDER BY id",
+			sqlStateColumns, rep.table, rep.dialect.Placeholder(1)), sqlTime(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	expired, err := scanStates(rows)
+	if err != nil {
+		return 0, err
+	}
+	return rep.remove(ctx, expired)
+}
+
+// StartSweeper
+//
+// Sweep expired states every interval until the context is done,
+// the Sweep errors are passed to OnSweepError if it is set
+func (rep *SQLStateRepository) StartSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := rep.Sweep(ctx); err != nil && ctx.Err() == nil && rep.OnSweepError != nil {
+					rep.OnSweepError(err)
+				}
+			}
+		}
+	}()
+}
+
+// remove
+//
+// Remove the expired states and call OnExpire for the removed ones.
+// A state removed by a concurrent read or Sweep is skipped, so OnExpire is called once for it.
+func (rep *SQLStateRepository) remove(ctx context.Context, expired []State) (int, error) {
+	removed := 0
+	for _, s := range expired {
+		res, err := rep.db.ExecContext(ctx,
+			fmt.Sprintf("DELETE FROM %s WHERE chat_id = %s AND message_id = %s AND state = %s AND version = %s AND expires_at = %s",
+				rep.table, rep.dialect.Placeholder(1), rep.dialect.Placeholder(2), rep.dialect.Placeholder(3),
+				rep.dialect.Placeholder(4), rep.dialect.Placeholder(5)),
+			s.ChatId, s.MessageId, s.State, s.Version, sqlTime(s.ExpiresAt))
+		if err != nil {
+			return removed, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			continue
+		}
+		removed++
+		if rep.OnExpire != nil {
+			rep.OnExpire(s)
+		}
+	}
+	return removed, nil
+}
+
+// query
+//
+// Active states matching the condition. Without OnExpire the expired states are skipped
+// and left to the Sweep, with OnExpire the expired states found are removed at once.
+func (rep *SQLStateRepository) query(ctx context.Context, where string, args ...interface{}) ([]State, error) {
+	now := time.Now()
+	if rep.OnExpire == nil {
+		where += fmt.Sprintf(" AND (expires_at = 0 OR expires_at > %s)", rep.dialect.Placeholder(len(args)+1))
+		args = append(args, sqlTime(now))
+	}
+	rows, err := rep.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY id", sqlStateColumns, rep.table, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	found, err := scanStates(rows)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]State, 0, len(found))
+	var expired []State
+	for _, s := range found {
+		if s.Expired(now) {
+			expired = append(expired, s)
+		} else {
+			states = append(states, s)
+		}
+	}
+	if _, err := rep.remove(ctx, expired); err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, ErrStateNotFound
+	}
+	return states, nil
+}
+
+func scanStates(rows *sql.Rows) ([]State, error) {
+	defer rows.Close()
+	var states []State
+	for rows.Next() {
+		s := State{}
+		var expiresAt int64
+		var data string
+		if err := rows.Scan(&s.ChatId, &s.MessageId, &s.Prefix, &s.Separator,
+			&s.State, &s.Action, &s.Key, &s.Value, &expiresAt, &s.Version, &data); err != nil {
+			return nil, err
+		}
+		if data != "" {
+			if err := json.Unmarshal([]byte(data), &s.Data); err != nil {
+				return nil, err
+			}
+		}
+		if expiresAt != 0 {
+			s.ExpiresAt = time.Unix(0, expiresAt)
+		}
+		states = append(states, s)
+	}
+	return states, rows.Err()
+}
+
+// sqlIdentifier
+//
+// The name is a letter or underscore followed by letters, digits and underscores
+func sqlIdentifier(name string) bool {
+	for i, r := range name {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !letter && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// sqlTime
+//
+// Unix time in nanoseconds, zero for the zero time
+func sqlTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// sqlData
+//
+// JSON of the state data, empty string for the empty data
+func sqlData(d Data) (string, error) {
+	if len(d) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(d)
+	return string(data), err
+}
+
+func (rep *SQLStateRepository) uniqueViolation(err error) bool {
+	return err != nil && rep.dialect.UniqueViolation != nil && rep.dialect.UniqueViolation(err)
+}
+
+func (rep *SQLStateRepository) transaction(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := rep.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w, rollback error: %v", err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func (rep *SQLStateRepository) placeholders(n int) string {
+	list := make([]string, n)
+	for i := range list {
+		list[i] = rep.dialect.Placeholder(i + 1)
+	}
+	return strings.Join(list, ", ")
+}
+
+func (rep *SQLStateRepository) migrate(ctx context.Context) error {
+	migrations := rep.table + "_migrations"
+	_, err := rep.db.ExecContext(ctx,
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version INTEGER PRIMARY KEY)", migrations))
+	if err != nil {
+		return err
+	}
+	var version sql.NullInt64
+	err = rep.db.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(version) FROM %s", migrations)).Scan(&version)
+	if err != nil {
+		return err
+	}
+	for v := int(version.Int64); v < len(sqlMigrations); v++ {
+		err := rep.transaction(ctx, func(tx *sql.Tx) error {
+			for _, stmt := range sqlMigrations[v](rep.dialect, rep.table) {
+				if _, err := tx.ExecContext(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			_, err := tx.ExecContext(ctx,
+				fmt.Sprintf("INSERT INTO %s (version) VALUES (%s)", migrations, rep.dialect.Placeholder(1)), v+1)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("state schema migration %d error: %w", v+1, err)
+		}
+	}
+	return nil
+}
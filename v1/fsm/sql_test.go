@@ -0,0 +1,376 @@
+package fsm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"modernc.org/sqlite"
+)
+
+// sqlTestRaces
+//
+// Number of the inserts preceded by a concurrent insert of the same state by the test trigger
+var sqlTestRaces int32
+
+func init() {
+	sqlite.MustRegisterScalarFunction("test_race", 0, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		if atomic.AddInt32(&sqlTestRaces, -1) >= 0 {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	})
+}
+
+func newTestSQLDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+t.TempDir()+"/states.db")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestNewSQLStateRepository_Migrate(t *testing.T) {
+	db := newTestSQLDB(t)
+	for i := 0; i < 2; i++ {
+		if _, err := NewSQLStateRepository(context.Background(), db, SQLiteDialect); err != nil {
+			t.Errorf("NewSQLStateRepository() error = %v, wantErr %v", err, nil)
+			return
+		}
+	}
+	var version int
+	if err := db.QueryRow("SELECT MAX(version) FROM fsm_states_migrations").Scan(&version); err != nil {
+		t.Errorf("select schema version error = %v", err)
+	}
+	if version != len(sqlMigrations) {
+		t.Errorf("NewSQLStateRepository() schema version = %d, want %d", version, len(sqlMigrations))
+	}
+
+	var indexes []string
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'fsm_states' ORDER BY name")
+	if err != nil {
+		t.Errorf("select indexes error = %v", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		indexes = append(indexes, name)
+	}
+	if diff := cmp.Diff(indexes, []string{"fsm_states_chat_message_idx", "fsm_states_expires_idx", "fsm_states_key_idx", "fsm_states_state_idx"}); diff != "" {
+		t.Errorf("NewSQLStateRepository() indexes difference: %v", diff)
+	}
+}
+
+func TestNewSQLStateRepositoryTable(t *testing.T) {
+	db := newTestSQLDB(t)
+	rep, err := NewSQLStateRepositoryTable(context.Background(), db, SQLiteDialect, "bot1_states")
+	if err != nil {
+		t.Errorf("NewSQLStateRepositoryTable() error = %v, wantErr %v", err, nil)
+		return
+	}
+	if err := rep.Set(State{ChatId: "100", State: "state1"}); err != nil {
+		t.Errorf("SQLStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM bot1_states").Scan(&count); err != nil || count != 1 {
+		t.Errorf("select states count = %d, error = %v, want %d", count, err, 1)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + DefaultSQLTable).Scan(&count); err == nil {
+		t.Errorf("NewSQLStateRepositoryTable() must not create the %s table", DefaultSQLTable)
+	}
+
+	for _, table := range []string{"", "1states", "states; DROP TABLE bot1_states", "bot-states"} {
+		if _, err := NewSQLStateRepositoryTable(context.Background(), db, SQLiteDialect, table); err == nil {
+			t.Errorf("NewSQLStateRepositoryTable() table %q must raise error", table)
+		}
+	}
+}
+
+func TestSQLStateRepository_Set_Rollback(t *testing.T) {
+	db := newTestSQLDB(t)
+	rep, err := NewSQLStateRepository(context.Background(), db, SQLiteDialect)
+	if err != nil {
+		t.Errorf("NewSQLStateRepository() error = %v, wantErr %v", err, nil)
+		return
+	}
+	st := State{ChatId: "100", MessageId: 10, State: "state1", Version: 1}
+	if err := rep.Set(st); err != nil {
+		t.Errorf("SQLStateRepository.Set() error = %v, wantErr %v", err, nil)
+		return
+	}
+
+	// The broken insert must not leave the chat without the previous state
+	rep.table = "fsm_states_missing"
+	if _, err := db.Exec("CREATE TABLE fsm_states_missing (chat_id TEXT)"); err != nil {
+		t.Errorf("create table error = %v", err)
+		return
+	}
+	if _, err := db.Exec("INSERT INTO fsm_states_missing (chat_id) VALUES ('100')"); err != nil {
+		t.Errorf("insert error = %v", err)
+		return
+	}
+	if err := rep.Set(State{ChatId: "100", MessageId: 11}); err == nil {
+		t.Error("SQLStateRepository.Set() broken insert must raise error")
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM fsm_states_missing").Scan(&count)
+	if count != 1 {
+		t.Errorf("SQLStateRepository.Set() rows after rollback = %d, want %d", count, 1)
+	}
+
+	rep.table = DefaultSQLTable
+	got, err := rep.Get("100")
+	if err != nil {
+		t.Errorf("SQLStateRepository.Get() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(got, []State{st}); diff != "" {
+		t.Errorf("SQLStateRepository.Get() difference: %v", diff)
+	}
+}
+
+func TestSQLStateRepository_Context(t *testing.T) {
+	rep, err := NewSQLStateRepository(context.Background(), newTestSQLDB(t), SQLiteDialect)
+	if err != nil {
+		t.Errorf("NewSQLStateRepository() error = %v, wantErr %v", err, nil)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := rep.SetContext(ctx, State{ChatId: "100"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("SQLStateRepository.SetContext() error = %v, wantErr %v", err, context.Canceled)
+	}
+}
+
+func TestSQLStateRepository_UniqueViolation(t *testing.T) {
+	db := newTestSQLDB(t)
+	rep, err := NewSQLStateRepository(context.Background(), db, SQLiteDialect)
+	if err != nil {
+		t.Errorf("NewSQLStateRepository() error = %v, wantErr %v", err, nil)
+		return
+	}
+	// The trigger inserts the same state right before the insert, as a concurrent Set
+	// committed between the DELETE and the INSERT does on Postgres
+	_, err = db.Exec(`CREATE TRIGGER fsm_states_race BEFORE INSERT ON fsm_states WHEN test_race() BEGIN
+		INSERT INTO fsm_states (chat_id, message_id, state) VALUES (NEW.chat_id, NEW.message_id, NEW.state); END`)
+	if err != nil {
+		t.Errorf("create trigger error = %v", err)
+		return
+	}
+	st := State{ChatId: "100", MessageId: 10, State: "state1", Key: "key1", Version: 1}
+
+	atomic.StoreInt32(&sqlTestRaces, 2)
+	if err := rep.Set(st); err != nil {
+		t.Errorf("SQLStateRepository.Set() error = %v, wantErr %v", err, nil)
+	}
+	got, err := rep.Get("100")
+	if err != nil {
+		t.Errorf("SQLStateRepository.Get() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(got, []State{st}); diff != "" {
+		t.Errorf("SQLStateRepository.Set() difference: %v", diff)
+	}
+
+	atomic.StoreInt32(&sqlTestRaces, maxUpdateAttempts)
+	if err := rep.Set(State{ChatId: "100", MessageId: 12, State: "state1"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("SQLStateRepository.Set() error = %v, wantErr %v", err, ErrConflict)
+	}
+
+	atomic.StoreInt32(&sqlTestRaces, 1)
+	old := State{ChatId: "100", MessageId: 11, State: "state1"}
+	if err := rep.CompareAndSet(old, old); !errors.Is(err, ErrConflict) {
+		t.Errorf("SQLStateRepository.CompareAndSet() error = %v, wantErr %v", err, ErrConflict)
+	}
+	atomic.StoreInt32(&sqlTestRaces, 0)
+}
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sql error " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestPostgresDialect_UniqueViolation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "SQLSTATE", err: fmt.Errorf("insert: %w", sqlStateError("23505")), want: true},
+		{name: "Other SQLSTATE", err: sqlStateError("23503")},
+		{name: "Message", err: errors.New(`pq: duplicate key value violates unique constraint "fsm_states_state_idx"`), want: true},
+		{name: "Other error", err: errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PostgresDialect.UniqueViolation(tt.err); got != tt.want {
+				t.Errorf("PostgresDialect.UniqueViolation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresDialect_Placeholder(t *testing.T) {
+	rep := SQLStateRepository{dialect: PostgresDialect}
+	if got := rep.placeholders(3); got != "$1, $2, $3" {
+		t.Errorf("SQLStateRepository.placeholders() = %s, want %s", got, "$1, $2, $3")
+	}
+}
+
+func TestSQLStateRepository_Set_Limits(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxChatStates int
+		replaceAll    bool
+		want          []State
+	}{
+		{
+			name: "Unlimited",
+			want: []State{
+				{ChatId: "100", MessageId: 10, State: "state1", Version: 1},
+				{ChatId: "100", MessageId: 11, State: "state1", Version: 1},
+				{ChatId: "100", MessageId: 12, State: "state1", Version: 1},
+			},
+		},
+		{
+			name:          "Evict oldest",
+			maxChatStates: 2,
+			want: []State{
+				{ChatId: "100", MessageId: 11, State: "state1", Version: 1},
+				{ChatId: "100", MessageId: 12, State: "state1", Version: 1},
+			},
+		},
+		{
+			name:       "Replace all",
+			replaceAll: true,
+			want:       []State{{ChatId: "100", MessageId: 12, State: "state1", Version: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep, err := NewSQLStateRepository(context.Background(), newTestSQLDB(t), SQLiteDialect)
+			if err != nil {
+				t.Errorf("NewSQLStateRepository() error = %v, wantErr %v", err, nil)
+				return
+			}
+			rep.MaxChatStates = tt.maxChatStates
+			rep.ReplaceAll = tt.replaceAll
+			rep.Set(State{ChatId: "101", MessageId: 10, State: "state1"})
+			for i := 10; i < 13; i++ {
+				if err := rep.Set(State{ChatId: "100", MessageId: i, State: "state1"}); err != nil {
+					t.Errorf("SQLStateRepository.Set() error = %v, wantErr %v", err, nil)
+					return
+				}
+			}
+			got, err := rep.Get("100")
+			if err != nil {
+				t.Errorf("SQLStateRepository.Get() error = %v, wantErr %v", err, nil)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("SQLStateRepository.Set() difference: %v", diff)
+			}
+			if _, err := rep.Get("101"); err != nil {
+				t.Errorf("SQLStateRepository.Set() other chat state error = %v, wantErr %v", err, nil)
+			}
+		})
+	}
+}
+
+func TestSQLStateRepository_Sweep(t *testing.T) {
+	rep, err := NewSQLStateRepository(context.Background(), newTestSQLDB(t), SQLiteDialect)
+	if err != nil {
+		t.Errorf("NewSQLStateRepository() error = %v, wantErr %v", err, nil)
+		return
+	}
+	var expired []State
+	rep.OnExpire = func(st State) { expired = append(expired, st) }
+	past := time.Unix(0, time.Now().Add(-time.Minute).UnixNano())
+	rep.Set(State{ChatId: "100", MessageId: 10, State: "state1", ExpiresAt: past})
+	rep.Set(State{ChatId: "100", MessageId: 11, State: "state1"})
+	rep.TTL = time.Hour
+	rep.Set(State{ChatId: "100", MessageId: 12, State: "state1"})
+
+	count, err := rep.Sweep(context.Background())
+	if err != nil {
+		t.Errorf("SQLStateRepository.Sweep() error = %v, wantErr %v", err, nil)
+	}
+	if count != 1 {
+		t.Errorf("SQLStateRepository.Sweep() = %d, want %d", count, 1)
+	}
+	if diff := cmp.Diff(expired, []State{{ChatId: "100", MessageId: 10, State: "state1", ExpiresAt: past, Version: 1}}); diff != "" {
+		t.Errorf("SQLStateRepository.Sweep() expired difference: %v", diff)
+	}
+	st, err := rep.GetByMessage("100", 12)
+	if err != nil {
+		t.Errorf("SQLStateRepository.GetByMessage() error = %v, wantErr %v", err, nil)
+	}
+	if st.ExpiresAt.IsZero() {
+		t.Error("SQLStateRepository.Set() state must expire after the TTL")
+	}
+}
+
+func TestSQLStateRepository_ExpireOnRead(t *testing.T) {
+	rep, err := NewSQLStateRepository(context.Background(), newTestSQLDB(t), SQLiteDialect)
+	if err != nil {
+		t.Errorf("NewSQLStateRepository() error = %v, wantErr %v", err, nil)
+		return
+	}
+	var expired []State
+	rep.OnExpire = func(st State) { expired = append(expired, st) }
+	past := time.Unix(0, time.Now().Add(-time.Minute).UnixNano())
+	rep.Set(State{ChatId: "100", MessageId: 10, State: "state1", ExpiresAt: past})
+	rep.Set(State{ChatId: "100", MessageId: 11, State: "state1"})
+
+	got, err := rep.Get("100")
+	if err != nil {
+		t.Errorf("SQLStateRepository.Get() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(got, []State{{ChatId: "100", MessageId: 11, State: "state1", Version: 1}}); diff != "" {
+		t.Errorf("SQLStateRepository.Get() difference: %v", diff)
+	}
+	if _, err := rep.GetByMessage("100", 10); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("SQLStateRepository.GetByMessage() error = %v, wantErr %v", err, ErrStateNotFound)
+	}
+	if count, _ := rep.Sweep(context.Background()); count != 0 {
+		t.Errorf("SQLStateRepository.Sweep() = %d, want %d", count, 0)
+	}
+	if diff := cmp.Diff(expired, []State{{ChatId: "100", MessageId: 10, State: "state1", ExpiresAt: past, Version: 1}}); diff != "" {
+		t.Errorf("SQLStateRepository.Get() expired difference: %v", diff)
+	}
+}
+
+func TestSQLStateRepository_StartSweeper(t *testing.T) {
+	db := newTestSQLDB(t)
+	rep, err := NewSQLStateRepository(context.Background(), db, SQLiteDialect)
+	if err != nil {
+		t.Errorf("NewSQLStateRepository() error = %v, wantErr %v", err, nil)
+		return
+	}
+	errs := make(chan error, 1)
+	rep.OnSweepError = func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rep.StartSweeper(ctx, 5*time.Millisecond)
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("SQLStateRepository.StartSweeper() error = nil, want error")
+		}
+	case <-time.After(time.Second):
+		t.Error("SQLStateRepository.StartSweeper() Sweep error was not reported")
+	}
+}
@@ -1,16 +1,19 @@
 package fsm
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
 	ErrStateNotFound    = errors.New("the state was not found in the repository")
 	ErrFailedToAddState = errors.New("failed to add the state to the repository")
 	ErrUpdateState      = errors.New("failed to update the state in the repository")
+	ErrConflict         = errors.New("the state was changed by another update")
 )
 
 func NewState() State {
@@ -18,14 +21,32 @@ func NewState() State {
 }
 
 type State struct {
-	Action    string `json:"action"`
-	ChatId    string `json:"chat_id"`
-	Key       string `json:"key"`
-	MessageId int    `json:"message_id"`
-	Prefix    string `json:"prefix"`
-	Separator string `json:"separator"`
-	State     string `json:"state"`
-	Value     string `json:"value"`
+	Action    string    `json:"action"`
+	ChatId    string    `json:"chat_id"`
+	Data      Data      `json:"data,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Key       string    `json:"key"`
+	MessageId int       `json:"message_id"`
+	Prefix    string    `json:"prefix"`
+	Separator string    `json:"separator"`
+	State     string    `json:"state"`
+	Value     string    `json:"value"`
+	Version   int       `json:"version"`
+}
+
+// Expired
+//
+// State with ExpiresAt is expired from that moment
+func (st State) Expired(now time.Time) bool {
+	return !st.ExpiresAt.IsZero() && !now.Before(st.ExpiresAt)
+}
+
+// WithTTL
+//
+// Copy of the state that expires after the ttl
+func (st State) WithTTL(ttl time.Duration) State {
+	st.ExpiresAt = time.Now().Add(ttl)
+	return st
 }
 
 func (st State) String() string {
@@ -66,14 +87,34 @@ func NewMemoryStateRepository() MemoryStateRepository {
 	return MemoryStateRepository{chatStates: make(map[string][]State)}
 }
 
+// MemoryStateRepository
+//
+// Keep the chat states in memory. A chat has one state for every (MessageId, State) pair,
+// the oldest states are evicted when MaxChatStates is exceeded. Every Set and CompareAndSet
+// stores the state with the next Version.
+// ReplaceAll keeps only the last set state of the chat.
+//
+// States without ExpiresAt expire after the TTL if it is set. Expired states are removed
+// when they are read or by the Sweep and OnExpire is called for every removed state,
+// e.g. to edit the abandoned menu message.
 type MemoryStateRepository struct {
-	chatStates map[string][]State
-	sync.Mutex
+	MaxChatStates int
+	ReplaceAll    bool
+	TTL           time.Duration
+	OnExpire      func(st State)
+	chatStates    map[string][]State
+	sync.RWMutex
 }
 
+// Get
+//
+// Copy of the chat states
 func (rep *MemoryStateRepository) Get(chatId string) (st []State, err error) {
+	rep.expire(chatId)
+	rep.RLock()
+	defer rep.RUnlock()
 	if states, ok := rep.chatStates[chatId]; ok {
-		return states, nil
+		return append(make([]State, 0, len(states)), states...), nil
 	}
 
 	return nil, ErrStateNotFound
@@ -93,7 +134,8 @@ func (rep *MemoryStateRepository) GetByMessage(chatId string, messageId int) (St
 }
 
 func (rep *MemoryStateRepository) GetByKey(key string) (slist []State, err error) {
-	rep.Mutex.Lock()
+	rep.Sweep()
+	rep.RLock()
 	for _, ss := range rep.chatStates {
 		for _, s := range ss {
 			if s.Key == key {
@@ -101,7 +143,7 @@ func (rep *MemoryStateRepository) GetByKey(key string) (slist []State, err error
 			}
 		}
 	}
-	rep.Mutex.Unlock()
+	rep.RUnlock()
 	if len(slist) == 0 {
 		return nil, ErrStateNotFound
 	}
@@ -112,14 +154,101 @@ func (rep *MemoryStateRepository) Set(s State) error {
 	if s.ChatId == "" {
 		return fmt.Errorf("State ChatId can't be empty, state: %v", s)
 	}
+	if s.ExpiresAt.IsZero() && rep.TTL > 0 {
+		s = s.WithTTL(rep.TTL)
+	}
+
+	rep.Lock()
+	defer rep.Unlock()
+	s.Version = rep.version(s) + 1
+	rep.set(s)
+	return nil
+}
+
+// CompareAndSet
+//
+// Replace the old state with the next one if the stored state with the old ChatId, MessageId
+// and State still has the old Version, otherwise return ErrConflict. Zero old Version expects
+// no stored state. The next state is stored with the next version.
+func (rep *MemoryStateRepository) CompareAndSet(old State, next State) error {
+	if next.ChatId == "" || next.ChatId != old.ChatId {
+		return fmt.Errorf("State ChatId can't be empty or changed, old state: %v, next state: %v", old, next)
+	}
+	if next.ExpiresAt.IsZero() && rep.TTL > 0 {
+		next = next.WithTTL(rep.TTL)
+	}
+
+	rep.Lock()
+	defer rep.Unlock()
+	if version := rep.version(old); version != old.Version {
+		return fmt.Errorf("%w: stored version %d, expected %d", ErrConflict, version, old.Version)
+	}
+
+	next.Version = old.Version + 1
+	rep.remove(old)
+	rep.set(next)
+	return nil
+}
+
+// version
+//
+// Version of the stored state with the same MessageId and State, zero if there is no such state
+func (rep *MemoryStateRepository) version(st State) int {
+	for _, s := range rep.chatStates[st.ChatId] {
+		if s.MessageId == st.MessageId && s.State == st.State && !s.Expired(time.Now()) {
+			return s.Version
+		}
+	}
+	return 0
+}
 
+// replace
+//
+// Replace the old state with the next one without the version check
+func (rep *MemoryStateRepository) replace(old State, next State) {
 	rep.Lock()
 	defer rep.Unlock()
+	rep.remove(old)
+	rep.set(next)
+}
+
+func (rep *MemoryStateRepository) set(s State) {
 	if rep.chatStates == nil {
 		rep.chatStates = make(map[string][]State)
 	}
-	rep.chatStates[s.ChatId] = []State{s}
-	return nil
+	if rep.ReplaceAll {
+		rep.chatStates[s.ChatId] = []State{s}
+		return
+	}
+
+	states := make([]State, 0, len(rep.chatStates[s.ChatId])+1)
+	for _, cs := range rep.chatStates[s.ChatId] {
+		if cs.MessageId != s.MessageId || cs.State != s.State {
+			states = append(states, cs)
+		}
+	}
+	states = append(states, s)
+	if rep.MaxChatStates > 0 && len(states) > rep.MaxChatStates {
+		states = states[len(states)-rep.MaxChatStates:]
+	}
+	rep.chatStates[s.ChatId] = states
+}
+
+// remove
+//
+// Remove the state with the same MessageId and State
+func (rep *MemoryStateRepository) remove(st State) {
+	states, ok := rep.chatStates[st.ChatId]
+	if !ok {
+		return
+	}
+	kept := make([]State, 0, len(states))
+	for _, s := range states {
+		if s.MessageId != st.MessageId || s.State != st.State {
+			kept = append(kept, s)
+		}
+	}
+	rep.chatStates[st.ChatId] = kept
 }
 
 func (rep *MemoryStateRepository) Clear(st State) error {
@@ -132,21 +261,116 @@ func (rep *MemoryStateRepository) Clear(st State) error {
 
 	if rep.chatStates == nil {
 		rep.chatStates = make(map[string][]State)
-	} else {
-		if st.MessageId == 0 && st.State == "" {
-			delete(rep.chatStates, st.ChatId)
-			return nil
+		return nil
+	}
+	if st.MessageId == 0 && st.State == "" {
+		delete(rep.chatStates, st.ChatId)
+		return nil
+	}
+	states, ok := rep.chatStates[st.ChatId]
+	if !ok {
+		return nil
+	}
+	// The states are filtered to a new slice, so copies returned by Get are never changed
+	kept := make([]State, 0, len(states))
+	for _, s := range states {
+		if st.MessageId != 0 && s.MessageId == st.MessageId {
+			continue
 		}
-		for k, v := range rep.chatStates {
-			for i, s := range v {
-				if st.MessageId != 0 && s.ChatId == st.ChatId && s.MessageId == st.MessageId {
-					rep.chatStates[k] = append(v[:i], v[i+1:]...)
-				}
-				if st.MessageId == 0 && s.ChatId == st.ChatId && s.State == st.State {
-					rep.chatStates[k] = append(v[:i], v[i+1:]...)
-				}
-			}
+		if st.MessageId == 0 && s.State == st.State {
+			continue
 		}
+		kept = append(kept, s)
 	}
+	rep.chatStates[st.ChatId] = kept
 	return nil
 }
+
+// Sweep
+//
+// Remove expired states of all chats and return the number of removed states
+func (rep *MemoryStateRepository) Sweep() int {
+	rep.Lock()
+	var expired []State
+	for chatId := range rep.chatStates {
+		expired = append(expired, rep.removeExpired(chatId, time.Now())...)
+	}
+	rep.Unlock()
+	rep.notifyExpired(expired)
+	return len(expired)
+}
+
+// StartSweeper
+//
+// Sweep expired states every interval until the context is done
+func (rep *MemoryStateRepository) StartSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rep.Sweep()
+			}
+		}
+	}()
+}
+
+// expire
+//
+// Remove expired states of the chat, the write lock is taken only if there are any
+func (rep *MemoryStateRepository) expire(chatId string) {
+	now := time.Now()
+	rep.RLock()
+	found := false
+	for _, s := range rep.chatStates[chatId] {
+		if s.Expired(now) {
+			found = true
+			break
+		}
+	}
+	rep.RUnlock()
+	if !found {
+		return
+	}
+
+	rep.Lock()
+	expired := rep.removeExpired(chatId, now)
+	rep.Unlock()
+	rep.notifyExpired(expired)
+}
+
+func (rep *MemoryStateRepository) removeExpired(chatId string, now time.Time) (expired []State) {
+	states, ok := rep.chatStates[chatId]
+	if !ok {
+		return nil
+	}
+	active := make([]State, 0, len(states))
+	for _, s := range states {
+		if s.Expired(now) {
+			expired = append(expired, s)
+		} else {
+			active = append(active, s)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	if len(active) == 0 {
+		delete(rep.chatStates, chatId)
+	} else {
+		rep.chatStates[chatId] = active
+	}
+	return expired
+}
+
+func (rep *MemoryStateRepository) notifyExpired(expired []State) {
+	if rep.OnExpire == nil {
+		return
+	}
+	for _, s := range expired {
+		rep.OnExpire(s)
+	}
+}
@@ -1,8 +1,12 @@
 package fsm
 
 import (
+	"context"
 	"sort"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -290,6 +294,8 @@ func TestMemoryStateRepository_Set(t *testing.T) {
 			if err != nil {
 				t.Errorf("MemoryStateRepository.Set() get state after setting error = %v", err)
 			}
+			// The state is stored with the next version
+			tt.st.Version = 1
 			if diff := cmp.Diff(got, tt.st); diff != "" {
 				t.Errorf("MemoryStateRepository.Set() = difference %v", diff)
 			}
@@ -372,3 +378,219 @@ func TestMemoryStateRepository_Clear(t *testing.T) {
 		})
 	}
 }
+
+func TestMemoryStateRepository_Set_Limits(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxChatStates int
+		replaceAll    bool
+		want          []State
+	}{
+		{
+			name: "Unlimited",
+			want: []State{
+				{ChatId: "100", MessageId: 10, State: "state1", Version: 1},
+				{ChatId: "100", MessageId: 11, State: "state1", Version: 1},
+				{ChatId: "100", MessageId: 12, State: "state1", Version: 1},
+			},
+		},
+		{
+			name:          "Evict oldest",
+			maxChatStates: 2,
+			want: []State{
+				{ChatId: "100", MessageId: 11, State: "state1", Version: 1},
+				{ChatId: "100", MessageId: 12, State: "state1", Version: 1},
+			},
+		},
+		{
+			name:       "Replace all",
+			replaceAll: true,
+			want:       []State{{ChatId: "100", MessageId: 12, State: "state1", Version: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep := NewMemoryStateRepository()
+			rep.MaxChatStates = tt.maxChatStates
+			rep.ReplaceAll = tt.replaceAll
+			for i := 10; i < 13; i++ {
+				if err := rep.Set(State{ChatId: "100", MessageId: i, State: "state1"}); err != nil {
+					t.Errorf("MemoryStateRepository.Set() error = %v, wantErr %v", err, nil)
+					return
+				}
+			}
+			if diff := cmp.Diff(rep.chatStates["100"], tt.want); diff != "" {
+				t.Errorf("MemoryStateRepository.Set() difference: %v", diff)
+			}
+		})
+	}
+}
+
+func TestMemoryStateRepository_Sweep(t *testing.T) {
+	var expired []State
+	rep := NewMemoryStateRepository()
+	rep.OnExpire = func(st State) { expired = append(expired, st) }
+	past := time.Now().Add(-time.Minute)
+	rep.Set(State{ChatId: "100", MessageId: 10, State: "state1", ExpiresAt: past})
+	rep.Set(State{ChatId: "100", MessageId: 11, State: "state1"})
+	rep.Set(State{ChatId: "101", MessageId: 12, State: "state1", ExpiresAt: past})
+	rep.TTL = time.Hour
+	rep.Set(State{ChatId: "102", MessageId: 13, State: "state1"})
+
+	if got := rep.Sweep(); got != 2 {
+		t.Errorf("MemoryStateRepository.Sweep() = %d, want %d", got, 2)
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ChatId < expired[j].ChatId })
+	want := []State{
+		{ChatId: "100", MessageId: 10, State: "state1", ExpiresAt: past, Version: 1},
+		{ChatId: "101", MessageId: 12, State: "state1", ExpiresAt: past, Version: 1},
+	}
+	if diff := cmp.Diff(expired, want); diff != "" {
+		t.Errorf("MemoryStateRepository.Sweep() expired difference: %v", diff)
+	}
+	if _, ok := rep.chatStates["101"]; ok {
+		t.Error("MemoryStateRepository.Sweep() chat without states must be removed")
+	}
+	if rep.chatStates["102"][0].ExpiresAt.IsZero() {
+		t.Error("MemoryStateRepository.Set() state must expire after the TTL")
+	}
+}
+
+func TestMemoryStateRepository_Get_Expired(t *testing.T) {
+	var expired []State
+	rep := NewMemoryStateRepository()
+	rep.OnExpire = func(st State) { expired = append(expired, st) }
+	st := State{ChatId: "100", MessageId: 10, State: "state1", ExpiresAt: time.Now().Add(-time.Minute), Version: 1}
+	rep.Set(st)
+
+	if _, err := rep.GetByMessage("100", 10); err == nil {
+		t.Error("MemoryStateRepository.GetByMessage() expired state must not be found")
+	}
+	if diff := cmp.Diff(expired, []State{st}); diff != "" {
+		t.Errorf("MemoryStateRepository.GetByMessage() expired difference: %v", diff)
+	}
+}
+
+func TestMemoryStateRepository_StartSweeper(t *testing.T) {
+	expired := make(chan State, 1)
+	rep := NewMemoryStateRepository()
+	rep.OnExpire = func(st State) { expired <- st }
+	rep.Set(State{ChatId: "100", State: "state1", ExpiresAt: time.Now().Add(10 * time.Millisecond)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rep.StartSweeper(ctx, 5*time.Millisecond)
+	select {
+	case st := <-expired:
+		if st.ChatId != "100" {
+			t.Errorf("MemoryStateRepository.StartSweeper() expired chat = %s, want %s", st.ChatId, "100")
+		}
+	case <-time.After(time.Second):
+		t.Error("MemoryStateRepository.StartSweeper() state was not expired")
+	}
+}
+
+func TestMemoryStateRepository_Get_Copy(t *testing.T) {
+	rep := NewMemoryStateRepository()
+	rep.Set(State{ChatId: "100", MessageId: 10, State: "state1"})
+	got, err := rep.Get("100")
+	if err != nil {
+		t.Errorf("MemoryStateRepository.Get() error = %v, wantErr %v", err, nil)
+		return
+	}
+	got[0].State = "changed"
+	got = append(got, State{ChatId: "100", MessageId: 11})
+
+	want := []State{{ChatId: "100", MessageId: 10, State: "state1", Version: 1}}
+	if diff := cmp.Diff(rep.chatStates["100"], want); diff != "" {
+		t.Errorf("MemoryStateRepository.Get() returned states must be a copy, difference: %v", diff)
+	}
+}
+
+func TestMemoryStateRepository_Clear_Adjacent(t *testing.T) {
+	tests := []struct {
+		name  string
+		clear State
+		want  []State
+	}{
+		{
+			name:  "Adjacent message states",
+			clear: State{ChatId: "100", MessageId: 10},
+			want:  []State{{ChatId: "100", MessageId: 12, State: "state2", Version: 1}, {ChatId: "100", MessageId: 11, State: "state1", Version: 1}},
+		},
+		{
+			name:  "Adjacent named states",
+			clear: State{ChatId: "100", State: "state2"},
+			want:  []State{{ChatId: "100", MessageId: 10, State: "state1", Version: 1}, {ChatId: "100", MessageId: 11, State: "state1", Version: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep := NewMemoryStateRepository()
+			for _, st := range []State{
+				{ChatId: "100", MessageId: 10, State: "state1"},
+				{ChatId: "100", MessageId: 10, State: "state2"},
+				{ChatId: "100", MessageId: 12, State: "state2"},
+				{ChatId: "100", MessageId: 11, State: "state1"},
+			} {
+				rep.Set(st)
+			}
+			before, _ := rep.Get("100")
+
+			if err := rep.Clear(tt.clear); err != nil {
+				t.Errorf("MemoryStateRepository.Clear() error = %v, wantErr %v", err, nil)
+				return
+			}
+			got, _ := rep.Get("100")
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("MemoryStateRepository.Clear() difference: %v", diff)
+			}
+			after, _ := rep.Get("100")
+			if len(before) == len(after) {
+				t.Errorf("MemoryStateRepository.Clear() states copy before clear was changed")
+			}
+		})
+	}
+}
+
+func TestMemoryStateRepository_Concurrent(t *testing.T) {
+	rep := NewMemoryStateRepository()
+	rep.MaxChatStates = 5
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			chatId := strconv.Itoa(i % 3)
+			for j := 0; j < 200; j++ {
+				st := State{ChatId: chatId, MessageId: j % 7, State: "state1", Key: "key1"}
+				if j%11 == 0 {
+					st.ExpiresAt = time.Now().Add(-time.Second)
+				}
+				rep.Set(st)
+				if states, err := rep.Get(chatId); err == nil && len(states) > 0 {
+					states[0].Key = "changed"
+				}
+				rep.GetByMessage(chatId, j%7)
+				rep.GetByKey("key1")
+				if j%5 == 0 {
+					rep.Clear(State{ChatId: chatId, MessageId: j % 7})
+				}
+				if j%13 == 0 {
+					rep.Sweep()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	states, _ := rep.GetByKey("changed")
+	if len(states) != 0 {
+		t.Errorf("MemoryStateRepository states changed through Get copies: %v", states)
+	}
+	for chatId, states := range rep.chatStates {
+		if len(states) > rep.MaxChatStates {
+			t.Errorf("MemoryStateRepository chat %s states = %d, max %d", chatId, len(states), rep.MaxChatStates)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package paginator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/alex13th/telebot/v1/fsm"
+	"github.com/alex13th/telebot/v1/router"
+	"github.com/alex13th/telebot/v1/telegram"
+)
+
+const (
+	DefaultState    string = "list"
+	DefaultPageSize int    = 10
+	PageAction      string = "page"
+	SelectAction    string = "select"
+	CurrentAction   string = "current"
+)
+
+// Item
+//
+// Text of the item button and the Key passed to the select handler as the state Value
+type Item struct {
+	Text string
+	Key  string
+}
+
+// FetchFunc
+//
+// Return the items of the page starting from zero and the total number of items
+type FetchFunc func(ctx context.Context, page int, size int) (items []Item, total int, err error)
+
+// SelectFunc
+//
+// Handle the selected item, the state Key is the page and the Value is the item Key
+type SelectFunc func(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error
+
+// SliceFetch
+//
+// Fetch the pages of the items slice
+func SliceFetch(items []Item) FetchFunc {
+	return func(ctx context.Context, page int, size int) ([]Item, int, error) {
+		start := page * size
+		if start > len(items) {
+			start = len(items)
+		}
+		end := start + size
+		if end > len(items) {
+			end = len(items)
+		}
+		return items[start:end], len(items), nil
+	}
+}
+
+func NewPaginator(prefix string, fetch FetchFunc, onSelect SelectFunc) Paginator {
+	return Paginator{
+		Prefix:   prefix,
+		State:    DefaultState,
+		PageSize: DefaultPageSize,
+		Columns:  1,
+		PrevText: "« Prev",
+		NextText: "Next »",
+		Fetch:    fetch,
+		OnSelect: onSelect,
+	}
+}
+
+// Paginator
+//
+// Keyboard of a page of items with the item buttons wrapped to Columns and the
+// Prev, page number and Next navigation row. Buttons are fsm states with the Prefix
+// and the State, the page is the Key of the states. The paginator handles the
+// navigation callbacks by editing the message keyboard and passes the selected
+// items to the OnSelect.
+type Paginator struct {
+	Prefix   string
+	State    string
+	PageSize int
+	Columns  int
+	PrevText string
+	NextText string
+	Codec    fsm.Codec
+	Fetch    FetchFunc
+	OnSelect SelectFunc
+}
+
+// Register
+//
+// Add the paginator callback handler to the router
+func (p Paginator) Register(r *router.Router) {
+	r.Callback(p.Prefix, p.HandleCallback)
+}
+
+// Keyboard
+//
+// Keyboard of the page, the page is limited to the existing pages
+func (p Paginator) Keyboard(ctx context.Context, page int) (telegram.InlineKeyboardMarkup, error) {
+	size := p.PageSize
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	if page < 0 {
+		page = 0
+	}
+	items, total, err := p.Fetch(ctx, page, size)
+	if err != nil {
+		return telegram.InlineKeyboardMarkup{}, err
+	}
+	pages := (total + size - 1) / size
+	if page >= pages && pages > 0 {
+		page = pages - 1
+		if items, total, err = p.Fetch(ctx, page, size); err != nil {
+			return telegram.InlineKeyboardMarkup{}, err
+		}
+	}
+
+	kb := telegram.NewInlineKeyboard().Wrap(p.Columns)
+	kb.Codec = p.Codec
+	for _, item := range items {
+		kb.Button(item.Text, p.button(SelectAction, page, item.Key))
+	}
+	if pages > 1 {
+		kb.Wrap(0).Row()
+		if page > 0 {
+			kb.Button(p.PrevText, p.button(PageAction, page-1, ""))
+		}
+		kb.Button(fmt.Sprintf("%d/%d", page+1, pages), p.button(CurrentAction, page, ""))
+		if page < pages-1 {
+			kb.Button(p.NextText, p.button(PageAction, page+1, ""))
+		}
+	}
+	return kb.Build()
+}
+
+// HandleCallback
+//
+// Show the page of the navigation callback or pass the selected item to the OnSelect
+func (p Paginator) HandleCallback(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error {
+	if st.State != p.State {
+		return router.ErrNotHandled
+	}
+	switch st.Action {
+	case SelectAction:
+		if p.OnSelect == nil {
+			return router.ErrNotHandled
+		}
+		return p.OnSelect(ctx, b, cq, st)
+	case PageAction:
+		page, err := strconv.Atoi(st.Key)
+		if err != nil {
+			return fmt.Errorf("incorrect page %s: '%w'", st.Key, err)
+		}
+		kbd, err := p.Keyboard(ctx, page)
+		if err != nil {
+			return err
+		}
+		if _, err := cq.Message.EditKeyboard(ctx, b, kbd); err != nil {
+			return err
+		}
+	case CurrentAction:
+	default:
+		return router.ErrNotHandled
+	}
+	_, err := cq.Answer(ctx, b, "")
+	return err
+}
+
+func (p Paginator) button(action string, page int, key string) fsm.State {
+	st := fsm.NewState()
+	st.Prefix = p.Prefix
+	st.State = p.State
+	st.Action = action
+	st.Key = strconv.Itoa(page)
+	st.Value = key
+	return st
+}
@@ -0,0 +1,139 @@
+package paginator
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/alex13th/telebot/v1/fsm"
+	"github.com/alex13th/telebot/v1/router"
+	"github.com/alex13th/telebot/v1/telegram"
+	"github.com/google/go-cmp/cmp"
+)
+
+type botMock struct {
+	requests []telegram.Request
+	err      error
+}
+
+func (bm *botMock) GetUpdates(ctx context.Context, ur telegram.UpdatesRequest) (telegram.UpdateResponse, error) {
+	return telegram.UpdateResponse{}, bm.err
+}
+
+func (bm *botMock) Send(ctx context.Context, r telegram.Request) (telegram.MessageResponse, error) {
+	bm.requests = append(bm.requests, r)
+	return telegram.MessageResponse{}, bm.err
+}
+
+func testItems(n int) []Item {
+	items := make([]Item, n)
+	for i := range items {
+		items[i] = Item{Text: "Item " + strconv.Itoa(i), Key: strconv.Itoa(i)}
+	}
+	return items
+}
+
+func TestPaginator_Keyboard(t *testing.T) {
+	p := NewPaginator("pg", SliceFetch(testItems(5)), nil)
+	p.PageSize = 2
+	p.Columns = 2
+	tests := []struct {
+		name string
+		page int
+		want [][]telegram.InlineKeyboardButton
+	}{
+		{
+			name: "First page",
+			page: 0,
+			want: [][]telegram.InlineKeyboardButton{
+				{{Text: "Item 0", CallbackData: "pg_list_select_0_0"}, {Text: "Item 1", CallbackData: "pg_list_select_0_1"}},
+				{{Text: "1/3", CallbackData: "pg_list_current_0"}, {Text: "Next »", CallbackData: "pg_list_page_1"}},
+			},
+		},
+		{
+			name: "Middle page",
+			page: 1,
+			want: [][]telegram.InlineKeyboardButton{
+				{{Text: "Item 2", CallbackData: "pg_list_select_1_2"}, {Text: "Item 3", CallbackData: "pg_list_select_1_3"}},
+				{{Text: "« Prev", CallbackData: "pg_list_page_0"}, {Text: "2/3", CallbackData: "pg_list_current_1"}, {Text: "Next »", CallbackData: "pg_list_page_2"}},
+			},
+		},
+		{
+			name: "Page after the last one",
+			page: 7,
+			want: [][]telegram.InlineKeyboardButton{
+				{{Text: "Item 4", CallbackData: "pg_list_select_2_4"}},
+				{{Text: "« Prev", CallbackData: "pg_list_page_1"}, {Text: "3/3", CallbackData: "pg_list_current_2"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Keyboard(context.Background(), tt.page)
+			if err != nil {
+				t.Errorf("Paginator.Keyboard() error = %v, wantErr %v", err, nil)
+				return
+			}
+			if diff := cmp.Diff(got, telegram.InlineKeyboardMarkup{InlineKeyboard: tt.want}); diff != "" {
+				t.Errorf("Paginator.Keyboard() difference: %v", diff)
+			}
+		})
+	}
+
+	single := NewPaginator("pg", SliceFetch(testItems(1)), nil)
+	got, err := single.Keyboard(context.Background(), 0)
+	if err != nil {
+		t.Errorf("Paginator.Keyboard() error = %v, wantErr %v", err, nil)
+	}
+	if len(got.InlineKeyboard) != 1 {
+		t.Errorf("Paginator.Keyboard() single page rows = %d, want %d", len(got.InlineKeyboard), 1)
+	}
+
+	fetchErr := errors.New("fetch error")
+	failed := NewPaginator("pg", func(ctx context.Context, page, size int) ([]Item, int, error) { return nil, 0, fetchErr }, nil)
+	if _, err := failed.Keyboard(context.Background(), 0); !errors.Is(err, fetchErr) {
+		t.Errorf("Paginator.Keyboard() error = %v, wantErr %v", err, fetchErr)
+	}
+}
+
+func TestPaginator_HandleCallback(t *testing.T) {
+	var selected fsm.State
+	p := NewPaginator("pg", SliceFetch(testItems(5)), func(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error {
+		selected = st
+		return nil
+	})
+	p.PageSize = 2
+	r := router.NewRouter()
+	p.Register(&r)
+	msg := telegram.Message{MessageId: 100, Chat: telegram.Chat{Id: 10}}
+
+	bm := &botMock{}
+	if err := r.ProceedCallback(context.Background(), bm, telegram.CallbackQuery{Id: "1", Data: "pg_list_page_1", Message: msg}); err != nil {
+		t.Errorf("Paginator.HandleCallback() error = %v, wantErr %v", err, nil)
+	}
+	kbd, _ := p.Keyboard(context.Background(), 1)
+	want := []telegram.Request{
+		telegram.EditMessageReplyMarkup{ChatId: 10, MessageId: 100, ReplyMarkup: kbd},
+		telegram.AnswerCallbackQuery{CallbackQueryId: "1"},
+	}
+	if diff := cmp.Diff(bm.requests, want); diff != "" {
+		t.Errorf("Paginator.HandleCallback() requests difference: %v", diff)
+	}
+
+	if err := r.ProceedCallback(context.Background(), bm, telegram.CallbackQuery{Id: "2", Data: "pg_list_select_1_3", Message: msg}); err != nil {
+		t.Errorf("Paginator.HandleCallback() error = %v, wantErr %v", err, nil)
+	}
+	if selected.Key != "1" || selected.Value != "3" {
+		t.Errorf("Paginator.HandleCallback() selected = %v, want page %s item %s", selected, "1", "3")
+	}
+
+	for _, data := range []string{"pg_other_page_1", "pg_list_unknown_1"} {
+		if err := r.ProceedCallback(context.Background(), bm, telegram.CallbackQuery{Id: "3", Data: data, Message: msg}); !errors.Is(err, router.ErrNotHandled) {
+			t.Errorf("Paginator.HandleCallback() error = %v, wantErr %v", err, router.ErrNotHandled)
+		}
+	}
+	if err := r.ProceedCallback(context.Background(), bm, telegram.CallbackQuery{Id: "4", Data: "pg_list_page_x", Message: msg}); err == nil {
+		t.Error("Paginator.HandleCallback() incorrect page must raise error")
+	}
+}
@@ -0,0 +1,275 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alex13th/telebot/v1/fsm"
+	"github.com/alex13th/telebot/v1/telegram"
+)
+
+// BackAction
+//
+// Action of a screen callback that returns the message to the previous screen
+const BackAction = "back"
+
+var (
+	ErrNotHandled = errors.New("the update was not handled")
+)
+
+type CallbackHandlerFunc func(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error
+
+type MessageHandlerFunc func(ctx context.Context, b telegram.Bot, msg telegram.Message) error
+
+type JoinRequestHandlerFunc func(ctx context.Context, b telegram.Bot, jr telegram.ChatJoinRequest) error
+
+func NewRouter() Router {
+	return Router{State: fsm.NewState(), callbacks: make(map[string]CallbackHandlerFunc)}
+}
+
+// Router
+//
+// Dispatch callback queries by the Prefix of the callback data and
+// messages and chat join requests to their handlers in the order they were added.
+// Updates nobody handles are skipped. Callback data is decoded by the Codec,
+// by the State.Parse if the Codec is nil. A fsm.ChatCodec also checks the data
+// belongs to the chat, so forged signed data or the one copied from another chat
+// is not handled, expired data is rejected as well if the codec has MaxAge.
+//
+// Taps of the same button on the same message repeated within DuplicateWindow are answered
+// and dropped. A callback whose handler fails with fsm.ErrConflict lost the race
+// to a concurrent tap, so it is answered and dropped as well.
+//
+// Screen callbacks are pushed to the Navigation stack of their message, the BackAction
+// of a screen pops it and dispatches the previous screen to re-render it.
+type Router struct {
+	State           fsm.State
+	Codec           fsm.Codec
+	DuplicateWindow time.Duration
+	Navigation      *fsm.NavigationStack
+	callbacks       map[string]CallbackHandlerFunc
+	screens         map[string]bool
+	messages        []MessageHandlerFunc
+	joinRequests    []JoinRequestHandlerFunc
+	taps            map[string]time.Time
+	tapsMutex       sync.Mutex
+}
+
+// Callback
+//
+// Add a handler for callback data with the prefix
+func (r *Router) Callback(prefix string, h CallbackHandlerFunc) {
+	if r.callbacks == nil {
+		r.callbacks = make(map[string]CallbackHandlerFunc)
+	}
+	r.callbacks[prefix] = h
+}
+
+// Screen
+//
+// Add a handler for callback data with the prefix that renders a screen of the message.
+// Handled screens are pushed to the Navigation stack.
+func (r *Router) Screen(prefix string, h CallbackHandlerFunc) {
+	r.Callback(prefix, h)
+	if r.screens == nil {
+		r.screens = make(map[string]bool)
+	}
+	r.screens[prefix] = true
+}
+
+// Message
+//
+// Add a message handler. A handler returns ErrNotHandled to pass the message to the next one.
+func (r *Router) Message(h MessageHandlerFunc) {
+	r.messages = append(r.messages, h)
+}
+
+// JoinRequest
+//
+// Add a chat join request handler. A handler returns ErrNotHandled to pass the request to the next one.
+func (r *Router) JoinRequest(h JoinRequestHandlerFunc) {
+	r.joinRequests = append(r.joinRequests, h)
+}
+
+func (r *Router) Proceed(ctx context.Context, b telegram.Bot, updates ...telegram.Update) error {
+	for _, u := range updates {
+		var err error
+		if u.CallbackQuery.Id != "" {
+			err = r.ProceedCallback(ctx, b, u.CallbackQuery)
+		} else if u.Message.MessageId != 0 {
+			err = r.ProceedMessage(ctx, b, u.Message)
+		} else if u.ChatJoinRequest.Date != 0 {
+			err = r.ProceedJoinRequest(ctx, b, u.ChatJoinRequest)
+		}
+		if err != nil && !errors.Is(err, ErrNotHandled) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Router) ProceedCallback(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery) error {
+	st, err := r.callbackState(cq)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotHandled, err)
+	}
+	h, ok := r.callbacks[st.Prefix]
+	if !ok {
+		return ErrNotHandled
+	}
+	if r.duplicate(cq, time.Now()) {
+		return r.drop(ctx, b, cq)
+	}
+	screen := r.screens[st.Prefix] && r.Navigation != nil
+	if screen && st.Action == BackAction {
+		err = r.back(ctx, b, cq, st)
+	} else {
+		err = h(ctx, b, cq, st)
+		if err == nil && screen {
+			err = r.Navigation.Push(st.ChatId, st.MessageId, st)
+		}
+	}
+	if errors.Is(err, fsm.ErrConflict) {
+		return r.drop(ctx, b, cq)
+	}
+	if err != nil {
+		return fmt.Errorf("proceed callback %s error: '%w'", cq.Data, err)
+	}
+	return nil
+}
+
+// back
+//
+// Pop the current screen of the message and dispatch the previous one
+func (r *Router) back(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error {
+	current, err := r.Navigation.Pop(st.ChatId, st.MessageId)
+	if errors.Is(err, fsm.ErrStateNotFound) {
+		return fmt.Errorf("%w: no current screen", ErrNotHandled)
+	}
+	if err != nil {
+		return err
+	}
+	prev, err := r.Navigation.Peek(st.ChatId, st.MessageId)
+	if errors.Is(err, fsm.ErrStateNotFound) {
+		// The first screen stays on the stack
+		if err := r.Navigation.Push(st.ChatId, st.MessageId, current); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: no previous screen", ErrNotHandled)
+	}
+	if err != nil {
+		return err
+	}
+	h, ok := r.callbacks[prev.Prefix]
+	if !ok {
+		return fmt.Errorf("%w: no handler of the previous screen %s", ErrNotHandled, prev.Prefix)
+	}
+	prev.ChatId = st.ChatId
+	prev.MessageId = st.MessageId
+	return h(ctx, b, cq, prev)
+}
+
+// duplicate
+//
+// Register the tap and report whether the same tap was registered within DuplicateWindow
+func (r *Router) duplicate(cq telegram.CallbackQuery, now time.Time) bool {
+	if r.DuplicateWindow <= 0 {
+		return false
+	}
+	r.tapsMutex.Lock()
+	defer r.tapsMutex.Unlock()
+	if r.taps == nil {
+		r.taps = make(map[string]time.Time)
+	}
+	for key, tapped := range r.taps {
+		if now.Sub(tapped) >= r.DuplicateWindow {
+			delete(r.taps, key)
+		}
+	}
+	key := fmt.Sprintf("%s:%d:%s", ChatId(cq.Message), cq.Message.MessageId, cq.Data)
+	if _, ok := r.taps[key]; ok {
+		return true
+	}
+	r.taps[key] = now
+	return false
+}
+
+// drop
+//
+// Answer the dropped callback query to stop the button spinner
+func (r *Router) drop(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery) error {
+	if _, err := cq.Answer(ctx, b, ""); err != nil {
+		return fmt.Errorf("drop callback %s error: '%w'", cq.Data, err)
+	}
+	return nil
+}
+
+func (r *Router) ProceedMessage(ctx context.Context, b telegram.Bot, msg telegram.Message) error {
+	for _, h := range r.messages {
+		err := h(ctx, b, msg)
+		if errors.Is(err, ErrNotHandled) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("proceed message %d error: '%w'", msg.MessageId, err)
+		}
+		return nil
+	}
+	return ErrNotHandled
+}
+
+func (r *Router) ProceedJoinRequest(ctx context.Context, b telegram.Bot, jr telegram.ChatJoinRequest) error {
+	for _, h := range r.joinRequests {
+		err := h(ctx, b, jr)
+		if errors.Is(err, ErrNotHandled) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("proceed join request of user %d error: '%w'", jr.From.Id, err)
+		}
+		return nil
+	}
+	return ErrNotHandled
+}
+
+func (r *Router) callbackState(cq telegram.CallbackQuery) (fsm.State, error) {
+	var st fsm.State
+	var err error
+	if cc, ok := r.Codec.(fsm.ChatCodec); ok {
+		st, err = cc.DecodeChat(ChatId(cq.Message), cq.Data)
+	} else if r.Codec != nil {
+		st, err = r.Codec.Decode(cq.Data)
+	} else {
+		parser := r.State
+		if parser.Separator == "" {
+			parser = fsm.NewState()
+		}
+		st, err = parser.Parse(cq.Data)
+	}
+	if err != nil {
+		return fsm.State{}, err
+	}
+	st.ChatId = ChatId(cq.Message)
+	st.MessageId = cq.Message.MessageId
+	return st, nil
+}
+
+// ChatId
+//
+// Chat id of the message in the form used by the state repository
+func ChatId(msg telegram.Message) string {
+	switch id := msg.Chat.Id.(type) {
+	case nil:
+		return ""
+	case int:
+		return strconv.Itoa(id)
+	case float64:
+		return strconv.FormatInt(int64(id), 10)
+	default:
+		return fmt.Sprint(id)
+	}
+}
@@ -0,0 +1,375 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alex13th/telebot/v1/fsm"
+	"github.com/alex13th/telebot/v1/telegram"
+	"github.com/google/go-cmp/cmp"
+)
+
+type botMock struct {
+	requests []telegram.Request
+	err      error
+}
+
+func (bm *botMock) GetUpdates(ctx context.Context, ur telegram.UpdatesRequest) (telegram.UpdateResponse, error) {
+	return telegram.UpdateResponse{}, bm.err
+}
+
+func (bm *botMock) Send(ctx context.Context, r telegram.Request) (telegram.MessageResponse, error) {
+	bm.requests = append(bm.requests, r)
+	return telegram.MessageResponse{}, bm.err
+}
+
+func TestRouter_ProceedCallback(t *testing.T) {
+	handlerErr := errors.New("handler error")
+	signedCodec := fsm.NewSignedCodec(fsm.PlainCodec{Separator: "_"}, []byte("secret"))
+	signedData, _ := signedCodec.Encode(fsm.State{ChatId: "10", Prefix: "menu", State: "main", Action: "open", Key: "key1"})
+	otherChatData, _ := signedCodec.Encode(fsm.State{ChatId: "11", Prefix: "menu", State: "main", Action: "open", Key: "key1"})
+	tests := []struct {
+		name    string
+		codec   fsm.Codec
+		data    string
+		err     error
+		want    fsm.State
+		wantErr error
+	}{
+		{
+			name: "Handled",
+			data: "menu_main_open_key1",
+			want: fsm.State{ChatId: "10", MessageId: 100, Prefix: "menu", Separator: "_", State: "main", Action: "open", Key: "key1"},
+		},
+		{
+			name:  "Codec",
+			codec: fsm.EscapeCodec{Separator: "_"},
+			data:  `menu_main_open_key\_1`,
+			want:  fsm.State{ChatId: "10", MessageId: 100, Prefix: "menu", Separator: "_", State: "main", Action: "open", Key: "key_1"},
+		},
+		{
+			name:  "Signed",
+			codec: signedCodec,
+			data:  signedData,
+			want:  fsm.State{ChatId: "10", MessageId: 100, Prefix: "menu", Separator: "_", State: "main", Action: "open", Key: "key1"},
+		},
+		{name: "Signed other chat", codec: signedCodec, data: otherChatData, wantErr: ErrNotHandled},
+		{name: "Unknown prefix", data: "other_main_open", wantErr: ErrNotHandled},
+		{name: "Incorrect data", data: "menu", wantErr: ErrNotHandled},
+		{name: "Handler error", data: "menu_main_open", err: handlerErr, wantErr: handlerErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got fsm.State
+			r := NewRouter()
+			r.Codec = tt.codec
+			r.Callback("menu", func(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error {
+				got = st
+				return tt.err
+			})
+			cq := telegram.CallbackQuery{
+				Id:      "1",
+				Data:    tt.data,
+				Message: telegram.Message{MessageId: 100, Chat: telegram.Chat{Id: 10}},
+			}
+			err := r.ProceedCallback(context.Background(), &botMock{}, cq)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Router.ProceedCallback() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Router.ProceedCallback() difference: %v", diff)
+			}
+		})
+	}
+}
+
+func TestRouter_ProceedMessage(t *testing.T) {
+	handlerErr := errors.New("handler error")
+	tests := []struct {
+		name     string
+		errs     []error
+		wantCall []int
+		wantErr  error
+	}{
+		{name: "First handled", errs: []error{nil, nil}, wantCall: []int{0}},
+		{name: "Passed to next", errs: []error{ErrNotHandled, nil}, wantCall: []int{0, 1}},
+		{name: "Nobody handled", errs: []error{ErrNotHandled, ErrNotHandled}, wantCall: []int{0, 1}, wantErr: ErrNotHandled},
+		{name: "Handler error", errs: []error{handlerErr, nil}, wantCall: []int{0}, wantErr: handlerErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []int
+			r := NewRouter()
+			for i, err := range tt.errs {
+				i, err := i, err
+				r.Message(func(ctx context.Context, b telegram.Bot, msg telegram.Message) error {
+					calls = append(calls, i)
+					return err
+				})
+			}
+			err := r.ProceedMessage(context.Background(), &botMock{}, telegram.Message{MessageId: 1})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Router.ProceedMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(calls, tt.wantCall); diff != "" {
+				t.Errorf("Router.ProceedMessage() calls difference: %v", diff)
+			}
+		})
+	}
+}
+
+func TestRouter_Proceed(t *testing.T) {
+	handlerErr := errors.New("handler error")
+	var callbacks, messages, joinRequests int
+	r := NewRouter()
+	r.Callback("menu", func(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error {
+		callbacks++
+		return nil
+	})
+	r.Message(func(ctx context.Context, b telegram.Bot, msg telegram.Message) error {
+		messages++
+		if msg.Text == "fail" {
+			return handlerErr
+		}
+		return nil
+	})
+	r.JoinRequest(func(ctx context.Context, b telegram.Bot, jr telegram.ChatJoinRequest) error {
+		joinRequests++
+		return nil
+	})
+	updates := []telegram.Update{
+		{UpdateId: 1, Message: telegram.Message{MessageId: 1, Text: "text"}},
+		{UpdateId: 2, CallbackQuery: telegram.CallbackQuery{Id: "2", Data: "menu_main_open"}},
+		{UpdateId: 3, CallbackQuery: telegram.CallbackQuery{Id: "3", Data: "unknown_main_open"}},
+		{UpdateId: 4},
+		{UpdateId: 5, ChatJoinRequest: telegram.ChatJoinRequest{Chat: telegram.Chat{Id: -100}, From: telegram.User{Id: 7}, Date: 1}},
+	}
+	if err := r.Proceed(context.Background(), &botMock{}, updates...); err != nil {
+		t.Errorf("Router.Proceed() error = %v, wantErr %v", err, nil)
+	}
+	if callbacks != 1 || messages != 1 || joinRequests != 1 {
+		t.Errorf("Router.Proceed() callbacks = %d, messages = %d, join requests = %d, want 1, 1 and 1",
+			callbacks, messages, joinRequests)
+	}
+
+	err := r.Proceed(context.Background(), &botMock{}, telegram.Update{Message: telegram.Message{MessageId: 1, Text: "fail"}})
+	if !errors.Is(err, handlerErr) {
+		t.Errorf("Router.Proceed() error = %v, wantErr %v", err, handlerErr)
+	}
+}
+
+func TestRouter_ProceedJoinRequest(t *testing.T) {
+	handlerErr := errors.New("handler error")
+	jr := telegram.ChatJoinRequest{Chat: telegram.Chat{Id: -100}, From: telegram.User{Id: 7}, Date: 1, Bio: "skip"}
+	tests := []struct {
+		name     string
+		handlers []error
+		wantErr  error
+		wantCall []int
+	}{
+		{name: "No handlers", wantErr: ErrNotHandled},
+		{name: "First handled", handlers: []error{nil, nil}, wantCall: []int{0}},
+		{name: "Passed to next", handlers: []error{ErrNotHandled, nil}, wantCall: []int{0, 1}},
+		{name: "Nobody handled", handlers: []error{ErrNotHandled}, wantErr: ErrNotHandled, wantCall: []int{0}},
+		{name: "Handler error", handlers: []error{handlerErr, nil}, wantErr: handlerErr, wantCall: []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []int
+			r := NewRouter()
+			for i, herr := range tt.handlers {
+				i, herr := i, herr
+				r.JoinRequest(func(ctx context.Context, b telegram.Bot, got telegram.ChatJoinRequest) error {
+					calls = append(calls, i)
+					if diff := cmp.Diff(got, jr); diff != "" {
+						t.Errorf("Router.ProceedJoinRequest() request difference: %v", diff)
+					}
+					return herr
+				})
+			}
+			err := r.ProceedJoinRequest(context.Background(), &botMock{}, jr)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("Router.ProceedJoinRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(calls, tt.wantCall); diff != "" {
+				t.Errorf("Router.ProceedJoinRequest() calls difference: %v", diff)
+			}
+		})
+	}
+}
+
+func TestRouter_Proceed_DecodedUpdate(t *testing.T) {
+	codec := fsm.NewSignedCodec(fsm.PlainCodec{Separator: "_"}, []byte("secret"))
+	data, _ := codec.Encode(fsm.State{ChatId: "123456789", Prefix: "menu", State: "main", Action: "open"})
+	var ur telegram.UpdateResponse
+	err := ur.Parse(strings.NewReader(`{"ok": true, "result": [{"update_id": 1, "callback_query": {
+		"id": "1", "from": {"id": 123456789, "first_name": "Alexey"}, "data": "` + data + `",
+		"message": {"message_id": 100, "chat": {"id": 123456789, "type": "private"}, "date": 1630134810, "text": "Menu"}}}]}`))
+	if err != nil {
+		t.Fatalf("UpdateResponse.Parse() error = %v", err)
+	}
+	var got fsm.State
+	r := NewRouter()
+	r.Codec = codec
+	r.Callback("menu", func(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error {
+		got = st
+		return nil
+	})
+	if err := r.Proceed(context.Background(), &botMock{}, ur.Result...); err != nil {
+		t.Errorf("Router.Proceed() error = %v, wantErr %v", err, nil)
+	}
+	want := fsm.State{ChatId: "123456789", MessageId: 100, Prefix: "menu", Separator: "_", State: "main", Action: "open"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Router.Proceed() state difference: %v", diff)
+	}
+}
+
+func TestRouter_ProceedCallback_ZeroState(t *testing.T) {
+	r := Router{}
+	r.Callback("menu", func(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error {
+		if st.State != "main" {
+			return fmt.Errorf("unexpected state %v", st)
+		}
+		return nil
+	})
+	wg := sync.WaitGroup{}
+	for i := 1; i <= 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cq := telegram.CallbackQuery{Id: "1", Data: "menu_main_open",
+				Message: telegram.Message{MessageId: i, Chat: telegram.Chat{Id: 100}}}
+			if err := r.ProceedCallback(context.Background(), &botMock{}, cq); err != nil {
+				t.Errorf("Router.ProceedCallback() error = %v, wantErr %v", err, nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if diff := cmp.Diff(fsm.State{}, r.State); diff != "" {
+		t.Errorf("Router.ProceedCallback() changed the router state: %v", diff)
+	}
+}
+
+func TestChatId(t *testing.T) {
+	tests := []struct {
+		name string
+		id   interface{}
+		want string
+	}{
+		{name: "Empty", want: ""},
+		{name: "Int", id: -1001234567890, want: "-1001234567890"},
+		{name: "Float", id: float64(123456789), want: "123456789"},
+		{name: "Username", id: "@channel", want: "@channel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChatId(telegram.Message{Chat: telegram.Chat{Id: tt.id}}); got != tt.want {
+				t.Errorf("ChatId() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouter_ProceedCallback_Duplicate(t *testing.T) {
+	tests := []struct {
+		name        string
+		window      time.Duration
+		data        []string
+		err         error
+		wantCalls   int
+		wantAnswers int
+	}{
+		{name: "Duplicates allowed", data: []string{"menu_main_open", "menu_main_open"}, wantCalls: 2},
+		{name: "Duplicate dropped", window: time.Minute, data: []string{"menu_main_open", "menu_main_open"}, wantCalls: 1, wantAnswers: 1},
+		{name: "Other button", window: time.Minute, data: []string{"menu_main_open", "menu_main_close"}, wantCalls: 2},
+		{name: "Conflict dropped", data: []string{"menu_main_open"}, err: fmt.Errorf("set: %w", fsm.ErrConflict), wantCalls: 1, wantAnswers: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			r := NewRouter()
+			r.DuplicateWindow = tt.window
+			r.Callback("menu", func(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error {
+				calls++
+				return tt.err
+			})
+			bm := &botMock{}
+			for _, data := range tt.data {
+				cq := telegram.CallbackQuery{
+					Id:      "1",
+					Data:    data,
+					Message: telegram.Message{MessageId: 100, Chat: telegram.Chat{Id: 10}},
+				}
+				if err := r.ProceedCallback(context.Background(), bm, cq); err != nil {
+					t.Errorf("Router.ProceedCallback() error = %v, wantErr %v", err, nil)
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Router.ProceedCallback() handler calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if len(bm.requests) != tt.wantAnswers {
+				t.Errorf("Router.ProceedCallback() answers = %d, want %d", len(bm.requests), tt.wantAnswers)
+			}
+		})
+	}
+}
+
+func TestRouter_ProceedCallback_DuplicateExpired(t *testing.T) {
+	r := NewRouter()
+	r.DuplicateWindow = time.Minute
+	cq := telegram.CallbackQuery{Id: "1", Data: "menu_main_open", Message: telegram.Message{MessageId: 100, Chat: telegram.Chat{Id: 10}}}
+	now := time.Now()
+	if r.duplicate(cq, now) {
+		t.Error("Router.duplicate() first tap must not be duplicate")
+	}
+	if !r.duplicate(cq, now.Add(time.Second)) {
+		t.Error("Router.duplicate() tap within window must be duplicate")
+	}
+	if r.duplicate(cq, now.Add(time.Minute)) {
+		t.Error("Router.duplicate() tap after window must not be duplicate")
+	}
+}
+
+func TestRouter_Screen_Back(t *testing.T) {
+	rep := fsm.NewMemoryStateRepository()
+	ns := fsm.NewNavigationStack(&rep)
+	r := NewRouter()
+	r.Navigation = &ns
+	var rendered []string
+	r.Screen("menu", func(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error {
+		rendered = append(rendered, st.State)
+		return nil
+	})
+	bm := &botMock{}
+	proceed := func(data string) error {
+		cq := telegram.CallbackQuery{Id: "1", Data: data, Message: telegram.Message{MessageId: 100, Chat: telegram.Chat{Id: 10}}}
+		return r.ProceedCallback(context.Background(), bm, cq)
+	}
+	for _, data := range []string{"menu_main_open", "menu_settings_open", "menu_language_open", "menu_language_back", "menu_settings_back"} {
+		if err := proceed(data); err != nil {
+			t.Errorf("Router.ProceedCallback() error = %v, wantErr %v", err, nil)
+		}
+	}
+	if err := proceed("menu_main_back"); !errors.Is(err, ErrNotHandled) {
+		t.Errorf("Router.ProceedCallback() first screen back error = %v, wantErr %v", err, ErrNotHandled)
+	}
+	if diff := cmp.Diff(rendered, []string{"main", "settings", "language", "settings", "main"}); diff != "" {
+		t.Errorf("Router.ProceedCallback() rendered screens difference: %v", diff)
+	}
+	top, err := ns.Peek("10", 100)
+	if err != nil {
+		t.Errorf("NavigationStack.Peek() error = %v, wantErr %v", err, nil)
+	}
+	if top.State != "main" {
+		t.Errorf("NavigationStack.Peek() = %v, want %v", top.State, "main")
+	}
+}
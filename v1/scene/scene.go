@@ -0,0 +1,365 @@
+package scene
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/alex13th/telebot/v1/fsm"
+	"github.com/alex13th/telebot/v1/router"
+	"github.com/alex13th/telebot/v1/telegram"
+)
+
+const (
+	DefaultPrefix        string = "scene"
+	DefaultCancelCommand string = "cancel"
+	BackAction           string = "back"
+	CancelAction         string = "cancel"
+)
+
+var (
+	ErrSceneNotFound  = errors.New("the scene was not found")
+	ErrStepNotFound   = errors.New("the scene step was not found")
+	ErrNoPreviousStep = errors.New("the scene has no previous step")
+	ErrSessionClosed  = errors.New("the scene session is already closed")
+)
+
+type HandlerFunc func(ctx context.Context, s *Session) error
+
+// Step
+//
+// Enter is called when the chat comes to the step, Handle for every message or
+// callback while the step is active and Leave when the chat goes away from it.
+type Step struct {
+	Name   string
+	Enter  HandlerFunc
+	Handle HandlerFunc
+	Leave  HandlerFunc
+}
+
+// Scene
+//
+// Named sequence of steps. A Timeout greater than zero closes the scene
+// when the chat is inactive for longer than the Timeout.
+type Scene struct {
+	Name      string
+	Steps     []Step
+	Timeout   time.Duration
+	OnCancel  HandlerFunc
+	OnTimeout HandlerFunc
+}
+
+func (sc Scene) stepIndex(name string) int {
+	for i, step := range sc.Steps {
+		if step.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func NewManager(rep fsm.StateRepository) Manager {
+	return Manager{
+		Prefix:        DefaultPrefix,
+		CancelCommand: DefaultCancelCommand,
+		repository:    rep,
+		scenes:        make(map[string]Scene),
+		now:           time.Now,
+	}
+}
+
+// Manager
+//
+// Keep the current scene step of every chat in the state repository and route
+// messages and callbacks with the manager Prefix to the active step.
+type Manager struct {
+	Prefix        string
+	CancelCommand string
+	repository    fsm.StateRepository
+	scenes        map[string]Scene
+	now           func() time.Time
+}
+
+func (m *Manager) Add(sc Scene) error {
+	if sc.Name == "" || len(sc.Steps) == 0 {
+		return fmt.Errorf("scene must have a name and at least one step, scene: %s", sc.Name)
+	}
+	if m.scenes == nil {
+		m.scenes = make(map[string]Scene)
+	}
+	m.scenes[sc.Name] = sc
+	return nil
+}
+
+// Register
+//
+// Add the manager callback and message handlers to the router
+func (m *Manager) Register(r *router.Router) {
+	r.Callback(m.Prefix, m.HandleCallback)
+	r.Message(m.HandleMessage)
+}
+
+// Start
+//
+// Start the scene in the message chat and enter its first step,
+// the scene active in the chat is dropped without its Leave
+func (m *Manager) Start(ctx context.Context, b telegram.Bot, msg telegram.Message, name string) error {
+	sc, ok := m.scenes[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSceneNotFound, name)
+	}
+	s := &Session{Bot: b, Message: msg, manager: m, scene: sc, step: -1}
+	s.State = fsm.NewState()
+	s.State.ChatId = router.ChatId(msg)
+	s.State.Prefix = m.Prefix
+	s.State.State = sc.Name
+	// The scene replaces the one active in the chat
+	states, err := m.repository.Get(s.State.ChatId)
+	if err != nil && !errors.Is(err, fsm.ErrStateNotFound) {
+		return err
+	}
+	for _, st := range states {
+		if st.Prefix == m.Prefix && st.State != sc.Name {
+			if err := m.repository.Clear(fsm.State{ChatId: st.ChatId, State: st.State}); err != nil {
+				return err
+			}
+		}
+	}
+	return s.enter(ctx, 0)
+}
+
+// Active
+//
+// Return the scene state of the chat if a scene is active
+func (m *Manager) Active(chatId string) (fsm.State, error) {
+	states, err := m.repository.Get(chatId)
+	if err != nil {
+		return fsm.State{}, err
+	}
+	for _, st := range states {
+		if st.Prefix == m.Prefix {
+			return st, nil
+		}
+	}
+	return fsm.State{}, fsm.ErrStateNotFound
+}
+
+func (m *Manager) HandleMessage(ctx context.Context, b telegram.Bot, msg telegram.Message) error {
+	s, err := m.session(ctx, b, msg)
+	if err != nil {
+		return err
+	}
+	if m.CancelCommand != "" && msg.GetCommand() == m.CancelCommand {
+		return s.Cancel(ctx)
+	}
+	return s.handle(ctx)
+}
+
+func (m *Manager) HandleCallback(ctx context.Context, b telegram.Bot, cq telegram.CallbackQuery, st fsm.State) error {
+	s, err := m.session(ctx, b, cq.Message)
+	if err != nil {
+		return err
+	}
+	if st.State != s.State.State {
+		return router.ErrNotHandled
+	}
+	s.Callback = cq
+	s.Data = st
+	switch st.Action {
+	case BackAction:
+		if s.step < 1 {
+			// Back on the first step is answered and ignored
+			_, err := cq.Answer(ctx, b, "")
+			return err
+		}
+		return s.Back(ctx)
+	case CancelAction:
+		return s.Cancel(ctx)
+	}
+	return s.handle(ctx)
+}
+
+func (m *Manager) session(ctx context.Context, b telegram.Bot, msg telegram.Message) (*Session, error) {
+	st, err := m.Active(router.ChatId(msg))
+	if err != nil {
+		return nil, router.ErrNotHandled
+	}
+	sc, ok := m.scenes[st.State]
+	if !ok {
+		return nil, router.ErrNotHandled
+	}
+	s := &Session{Bot: b, State: st, Message: msg, manager: m, scene: sc, step: sc.stepIndex(st.Action)}
+	if s.step < 0 {
+		// The stale state of a removed step is dropped, so the update isn't retried forever
+		if err := m.repository.Clear(st); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v: %s", router.ErrNotHandled, ErrStepNotFound, st.Action)
+	}
+	if s.expired() {
+		s.closed = true
+		if err := m.repository.Clear(st); err != nil {
+			return nil, err
+		}
+		if sc.OnTimeout != nil {
+			if err := sc.OnTimeout(ctx, s); err != nil {
+				return nil, err
+			}
+		}
+		return nil, router.ErrNotHandled
+	}
+	return s, nil
+}
+
+// Session
+//
+// Active scene of a chat together with the update being handled
+type Session struct {
+	Bot      telegram.Bot
+	State    fsm.State
+	Data     fsm.State
+	Message  telegram.Message
+	Callback telegram.CallbackQuery
+	manager  *Manager
+	scene    Scene
+	step     int
+	moved    bool
+	closed   bool
+}
+
+func (s *Session) Scene() Scene {
+	return s.scene
+}
+
+func (s *Session) Step() Step {
+	return s.scene.Steps[s.step]
+}
+
+// Button
+//
+// Callback state routed to the current scene of the chat
+func (s *Session) Button(action string, key string, value string) fsm.State {
+	st := fsm.NewState()
+	st.Prefix = s.manager.Prefix
+	st.State = s.scene.Name
+	st.Action = action
+	st.Key = key
+	st.Value = value
+	return st
+}
+
+// Next
+//
+// Leave the current step and enter the next one, finish the scene after the last step
+func (s *Session) Next(ctx context.Context) error {
+	if s.step+1 >= len(s.scene.Steps) {
+		return s.Finish(ctx)
+	}
+	return s.move(ctx, s.step+1)
+}
+
+func (s *Session) Back(ctx context.Context) error {
+	if s.step < 1 {
+		return ErrNoPreviousStep
+	}
+	return s.move(ctx, s.step-1)
+}
+
+func (s *Session) Goto(ctx context.Context, name string) error {
+	i := s.scene.stepIndex(name)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrStepNotFound, name)
+	}
+	return s.move(ctx, i)
+}
+
+// Cancel
+//
+// Leave the current step, close the scene and call its OnCancel
+func (s *Session) Cancel(ctx context.Context) error {
+	if err := s.Finish(ctx); err != nil {
+		return err
+	}
+	if s.scene.OnCancel != nil {
+		return s.scene.OnCancel(ctx, s)
+	}
+	return nil
+}
+
+// Finish
+//
+// Leave the current step and close the scene
+func (s *Session) Finish(ctx context.Context) error {
+	if err := s.leave(ctx); err != nil {
+		return err
+	}
+	s.closed = true
+	return s.manager.repository.Clear(fsm.State{ChatId: s.State.ChatId, State: s.State.State})
+}
+
+func (s *Session) handle(ctx context.Context) error {
+	step := s.Step()
+	if step.Handle != nil {
+		if err := step.Handle(ctx, s); err != nil {
+			return err
+		}
+	}
+	if s.moved || s.closed {
+		return nil
+	}
+	return s.save()
+}
+
+func (s *Session) move(ctx context.Context, i int) error {
+	if err := s.leave(ctx); err != nil {
+		return err
+	}
+	return s.enter(ctx, i)
+}
+
+func (s *Session) leave(ctx context.Context) error {
+	if s.closed {
+		return ErrSessionClosed
+	}
+	if s.step < 0 {
+		return nil
+	}
+	if step := s.Step(); step.Leave != nil {
+		return step.Leave(ctx, s)
+	}
+	return nil
+}
+
+func (s *Session) enter(ctx context.Context, i int) error {
+	s.step = i
+	s.moved = true
+	s.State.Action = s.Step().Name
+	if err := s.save(); err != nil {
+		return err
+	}
+	if step := s.Step(); step.Enter != nil {
+		return step.Enter(ctx, s)
+	}
+	return nil
+}
+
+func (s *Session) save() error {
+	s.State.Value = ""
+	if s.scene.Timeout > 0 {
+		s.State.Value = strconv.FormatInt(s.manager.now().Add(s.scene.Timeout).Unix(), 10)
+	}
+	return s.manager.repository.Set(s.State)
+}
+
+func (s *Session) expired() bool {
+	if s.State.Value == "" {
+		return false
+	}
+	deadline, err := strconv.ParseInt(s.State.Value, 10, 64)
+	if err != nil {
+		return false
+	}
+	return s.manager.now().Unix() > deadline
+}
@@ -0,0 +1,359 @@
+package scene
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alex13th/telebot/v1/fsm"
+	"github.com/alex13th/telebot/v1/router"
+	"github.com/alex13th/telebot/v1/telegram"
+	"github.com/google/go-cmp/cmp"
+)
+
+type botMock struct {
+	requests []telegram.Request
+	err      error
+}
+
+func (bm *botMock) GetUpdates(ctx context.Context, ur telegram.UpdatesRequest) (telegram.UpdateResponse, error) {
+	return telegram.UpdateResponse{}, bm.err
+}
+
+func (bm *botMock) Send(ctx context.Context, r telegram.Request) (telegram.MessageResponse, error) {
+	bm.requests = append(bm.requests, r)
+	return telegram.MessageResponse{}, bm.err
+}
+
+type sceneLog struct {
+	calls []string
+}
+
+func (l *sceneLog) hook(name string, next func(ctx context.Context, s *Session) error) HandlerFunc {
+	return func(ctx context.Context, s *Session) error {
+		l.calls = append(l.calls, name)
+		if next != nil {
+			return next(ctx, s)
+		}
+		return nil
+	}
+}
+
+func newTestManager(l *sceneLog, timeout time.Duration) (Manager, *fsm.MemoryStateRepository) {
+	rep := fsm.NewMemoryStateRepository()
+	m := NewManager(&rep)
+	next := func(ctx context.Context, s *Session) error { return s.Next(ctx) }
+	m.Add(Scene{
+		Name: "form",
+		Steps: []Step{
+			{Name: "name", Enter: l.hook("enter name", nil), Handle: l.hook("handle name", next), Leave: l.hook("leave name", nil)},
+			{Name: "phone", Enter: l.hook("enter phone", nil), Handle: l.hook("handle phone", next), Leave: l.hook("leave phone", nil)},
+		},
+		Timeout:   timeout,
+		OnCancel:  l.hook("cancel", nil),
+		OnTimeout: l.hook("timeout", nil),
+	})
+	return m, &rep
+}
+
+func TestManager_Add(t *testing.T) {
+	m := NewManager(nil)
+	if err := m.Add(Scene{Name: "empty"}); err == nil {
+		t.Error("Manager.Add() scene without steps must raise error")
+	}
+	if err := m.Add(Scene{Name: "one", Steps: []Step{{Name: "step"}}}); err != nil {
+		t.Errorf("Manager.Add() error = %v, wantErr %v", err, nil)
+	}
+}
+
+func TestManager_Start(t *testing.T) {
+	l := &sceneLog{}
+	m, _ := newTestManager(l, time.Minute)
+	msg := telegram.Message{MessageId: 1, Chat: telegram.Chat{Id: 10}}
+
+	if err := m.Start(context.Background(), &botMock{}, msg, "unknown"); !errors.Is(err, ErrSceneNotFound) {
+		t.Errorf("Manager.Start() error = %v, wantErr %v", err, ErrSceneNotFound)
+	}
+	if err := m.Start(context.Background(), &botMock{}, msg, "form"); err != nil {
+		t.Errorf("Manager.Start() error = %v, wantErr %v", err, nil)
+		return
+	}
+	st, err := m.Active("10")
+	if err != nil {
+		t.Errorf("Manager.Active() error = %v, wantErr %v", err, nil)
+		return
+	}
+	if st.State != "form" || st.Action != "name" {
+		t.Errorf("Manager.Start() state = %s, step = %s, want form and name", st.State, st.Action)
+	}
+	if diff := cmp.Diff(l.calls, []string{"enter name"}); diff != "" {
+		t.Errorf("Manager.Start() calls difference: %v", diff)
+	}
+}
+
+func TestManager_Start_ReplacesActive(t *testing.T) {
+	l := &sceneLog{}
+	m, rep := newTestManager(l, time.Minute)
+	m.Add(Scene{Name: "survey", Steps: []Step{{Name: "question", Handle: l.hook("handle question", nil)}}})
+	rep.Set(fsm.State{ChatId: "10", MessageId: 5, Prefix: "menu", State: "main"})
+	msg := telegram.Message{MessageId: 1, Chat: telegram.Chat{Id: 10}}
+	for _, name := range []string{"form", "survey"} {
+		if err := m.Start(context.Background(), &botMock{}, msg, name); err != nil {
+			t.Errorf("Manager.Start() error = %v, wantErr %v", err, nil)
+			return
+		}
+	}
+	states, _ := rep.Get("10")
+	if len(states) != 2 {
+		t.Errorf("Manager.Start() states = %v, want the menu and survey states", states)
+	}
+	msg.Text = "Yes"
+	if err := m.HandleMessage(context.Background(), &botMock{}, msg); err != nil {
+		t.Errorf("Manager.HandleMessage() error = %v, wantErr %v", err, nil)
+	}
+	if diff := cmp.Diff(l.calls, []string{"enter name", "handle question"}); diff != "" {
+		t.Errorf("Manager.HandleMessage() calls difference: %v", diff)
+	}
+}
+
+func TestManager_HandleMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		texts      []string
+		wantCalls  []string
+		wantStep   string
+		wantClosed bool
+	}{
+		{
+			name:      "Next step",
+			texts:     []string{"John"},
+			wantCalls: []string{"enter name", "handle name", "leave name", "enter phone"},
+			wantStep:  "phone",
+		},
+		{
+			name:       "Finish",
+			texts:      []string{"John", "+100"},
+			wantCalls:  []string{"enter name", "handle name", "leave name", "enter phone", "handle phone", "leave phone"},
+			wantClosed: true,
+		},
+		{
+			name:       "Cancel command",
+			texts:      []string{"/cancel"},
+			wantCalls:  []string{"enter name", "leave name", "cancel"},
+			wantClosed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &sceneLog{}
+			m, _ := newTestManager(l, time.Minute)
+			msg := telegram.Message{MessageId: 1, Chat: telegram.Chat{Id: 10}}
+			if err := m.Start(context.Background(), &botMock{}, msg, "form"); err != nil {
+				t.Errorf("Manager.Start() error = %v, wantErr %v", err, nil)
+				return
+			}
+			for _, text := range tt.texts {
+				msg.Text = text
+				if err := m.HandleMessage(context.Background(), &botMock{}, msg); err != nil {
+					t.Errorf("Manager.HandleMessage() error = %v, wantErr %v", err, nil)
+					return
+				}
+			}
+			if diff := cmp.Diff(l.calls, tt.wantCalls); diff != "" {
+				t.Errorf("Manager.HandleMessage() calls difference: %v", diff)
+			}
+			st, err := m.Active("10")
+			if tt.wantClosed {
+				if err == nil {
+					t.Errorf("Manager.HandleMessage() scene must be closed, but step %s is active", st.Action)
+				}
+				return
+			}
+			if st.Action != tt.wantStep {
+				t.Errorf("Manager.HandleMessage() step = %s, want %s", st.Action, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestManager_HandleMessage_NotHandled(t *testing.T) {
+	m, _ := newTestManager(&sceneLog{}, time.Minute)
+	msg := telegram.Message{MessageId: 1, Chat: telegram.Chat{Id: 10}, Text: "text"}
+	if err := m.HandleMessage(context.Background(), &botMock{}, msg); !errors.Is(err, router.ErrNotHandled) {
+		t.Errorf("Manager.HandleMessage() error = %v, wantErr %v", err, router.ErrNotHandled)
+	}
+}
+
+func TestManager_HandleMessage_Timeout(t *testing.T) {
+	l := &sceneLog{}
+	m, _ := newTestManager(l, time.Minute)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	msg := telegram.Message{MessageId: 1, Chat: telegram.Chat{Id: 10}}
+	if err := m.Start(context.Background(), &botMock{}, msg, "form"); err != nil {
+		t.Errorf("Manager.Start() error = %v, wantErr %v", err, nil)
+		return
+	}
+	now = now.Add(2 * time.Minute)
+	msg.Text = "John"
+	if err := m.HandleMessage(context.Background(), &botMock{}, msg); !errors.Is(err, router.ErrNotHandled) {
+		t.Errorf("Manager.HandleMessage() error = %v, wantErr %v", err, router.ErrNotHandled)
+	}
+	if diff := cmp.Diff(l.calls, []string{"enter name", "timeout"}); diff != "" {
+		t.Errorf("Manager.HandleMessage() calls difference: %v", diff)
+	}
+	if _, err := m.Active("10"); err == nil {
+		t.Error("Manager.HandleMessage() expired scene must be closed")
+	}
+}
+
+func TestManager_HandleCallback(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    string
+		state     string
+		wantCalls []string
+		wantStep  string
+		wantErr   error
+	}{
+		{
+			name:      "Back",
+			action:    BackAction,
+			state:     "form",
+			wantCalls: []string{"enter name", "handle name", "leave name", "enter phone", "leave phone", "enter name"},
+			wantStep:  "name",
+		},
+		{
+			name:      "Handle",
+			action:    "confirm",
+			state:     "form",
+			wantCalls: []string{"enter name", "handle name", "leave name", "enter phone", "handle phone", "leave phone"},
+		},
+		{
+			name:      "Cancel",
+			action:    CancelAction,
+			state:     "form",
+			wantCalls: []string{"enter name", "handle name", "leave name", "enter phone", "leave phone", "cancel"},
+		},
+		{
+			name:      "Other scene",
+			action:    "confirm",
+			state:     "other",
+			wantCalls: []string{"enter name", "handle name", "leave name", "enter phone"},
+			wantStep:  "phone",
+			wantErr:   router.ErrNotHandled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &sceneLog{}
+			m, _ := newTestManager(l, time.Minute)
+			r := router.NewRouter()
+			m.Register(&r)
+			msg := telegram.Message{MessageId: 1, Chat: telegram.Chat{Id: 10}}
+			if err := m.Start(context.Background(), &botMock{}, msg, "form"); err != nil {
+				t.Errorf("Manager.Start() error = %v, wantErr %v", err, nil)
+				return
+			}
+			msg.Text = "John"
+			if err := r.ProceedMessage(context.Background(), &botMock{}, msg); err != nil {
+				t.Errorf("Router.ProceedMessage() error = %v, wantErr %v", err, nil)
+				return
+			}
+
+			st := fsm.NewState()
+			st.Prefix = DefaultPrefix
+			st.State = tt.state
+			st.Action = tt.action
+			cq := telegram.CallbackQuery{Id: "1", Data: st.String(), Message: msg}
+			if err := r.ProceedCallback(context.Background(), &botMock{}, cq); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Router.ProceedCallback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(l.calls, tt.wantCalls); diff != "" {
+				t.Errorf("Manager.HandleCallback() calls difference: %v", diff)
+			}
+			active, _ := m.Active("10")
+			if active.Action != tt.wantStep {
+				t.Errorf("Manager.HandleCallback() step = %s, want %s", active.Action, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestManager_HandleCallback_FirstStepBack(t *testing.T) {
+	l := &sceneLog{}
+	m, _ := newTestManager(l, time.Minute)
+	r := router.NewRouter()
+	m.Register(&r)
+	msg := telegram.Message{MessageId: 1, Chat: telegram.Chat{Id: 10}}
+	if err := m.Start(context.Background(), &botMock{}, msg, "form"); err != nil {
+		t.Errorf("Manager.Start() error = %v, wantErr %v", err, nil)
+		return
+	}
+	st := fsm.NewState()
+	st.Prefix = DefaultPrefix
+	st.State = "form"
+	st.Action = BackAction
+	bm := &botMock{}
+	cq := telegram.CallbackQuery{Id: "1", Data: st.String(), Message: msg}
+	if err := r.Proceed(context.Background(), bm, telegram.Update{CallbackQuery: cq}); err != nil {
+		t.Errorf("Router.Proceed() error = %v, wantErr %v", err, nil)
+	}
+	want := []telegram.Request{telegram.AnswerCallbackQuery{CallbackQueryId: "1"}}
+	if diff := cmp.Diff(bm.requests, want); diff != "" {
+		t.Errorf("Manager.HandleCallback() requests difference: %v", diff)
+	}
+	if diff := cmp.Diff(l.calls, []string{"enter name"}); diff != "" {
+		t.Errorf("Manager.HandleCallback() calls difference: %v", diff)
+	}
+	active, _ := m.Active("10")
+	if active.Action != "name" {
+		t.Errorf("Manager.HandleCallback() step = %s, want %s", active.Action, "name")
+	}
+}
+
+func TestManager_HandleMessage_StaleStep(t *testing.T) {
+	m, rep := newTestManager(&sceneLog{}, time.Minute)
+	st := fsm.NewState()
+	st.ChatId = "10"
+	st.Prefix = DefaultPrefix
+	st.State = "form"
+	st.Action = "removed"
+	if err := rep.Set(st); err != nil {
+		t.Errorf("MemoryStateRepository.Set() error = %v, wantErr %v", err, nil)
+		return
+	}
+	msg := telegram.Message{MessageId: 1, Chat: telegram.Chat{Id: 10}, Text: "John"}
+	err := m.HandleMessage(context.Background(), &botMock{}, msg)
+	if !errors.Is(err, router.ErrNotHandled) {
+		t.Errorf("Manager.HandleMessage() error = %v, wantErr %v", err, router.ErrNotHandled)
+	}
+	if _, err := m.Active("10"); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("Manager.Active() error = %v, wantErr %v", err, fsm.ErrStateNotFound)
+	}
+}
+
+func TestSession_Back(t *testing.T) {
+	m, _ := newTestManager(&sceneLog{}, time.Minute)
+	msg := telegram.Message{MessageId: 1, Chat: telegram.Chat{Id: 10}}
+	if err := m.Start(context.Background(), &botMock{}, msg, "form"); err != nil {
+		t.Errorf("Manager.Start() error = %v, wantErr %v", err, nil)
+		return
+	}
+	s, err := m.session(context.Background(), &botMock{}, msg)
+	if err != nil {
+		t.Errorf("Manager.session() error = %v, wantErr %v", err, nil)
+		return
+	}
+	if err := s.Back(context.Background()); !errors.Is(err, ErrNoPreviousStep) {
+		t.Errorf("Session.Back() error = %v, wantErr %v", err, ErrNoPreviousStep)
+	}
+	if err := s.Goto(context.Background(), "unknown"); !errors.Is(err, ErrStepNotFound) {
+		t.Errorf("Session.Goto() error = %v, wantErr %v", err, ErrStepNotFound)
+	}
+	want := fsm.State{Prefix: DefaultPrefix, Separator: "_", State: "form", Action: "ok", Key: "1"}
+	if diff := cmp.Diff(s.Button("ok", "1", ""), want); diff != "" {
+		t.Errorf("Session.Button() difference: %v", diff)
+	}
+}
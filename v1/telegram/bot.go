@@ -1,9 +1,12 @@
 package telegram
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -34,6 +37,22 @@ type Request interface {
 	GetParams() (v url.Values, method string, err error)
 }
 
+// InputFile
+//
+// File uploaded with the request
+type InputFile struct {
+	Name   string
+	Reader io.Reader
+}
+
+// UploadRequest
+//
+// Request with the files, it is sent as multipart/form-data
+type UploadRequest interface {
+	Request
+	GetFiles() map[string]InputFile
+}
+
 type Response interface {
 	Parse(reader io.Reader) error
 }
@@ -90,18 +109,54 @@ func (sb SimpleBot) sendRequest(ctx context.Context, req Request) (*http.Respons
 
 	url := fmt.Sprintf("%s/bot%s/%s", sb.apiEndpoint, sb.token, method)
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(values.Encode()))
+	var body io.Reader = strings.NewReader(values.Encode())
+	contentType := "application/x-www-form-urlencoded"
+	if ur, ok := req.(UploadRequest); ok && len(ur.GetFiles()) > 0 {
+		if body, contentType, err = multipartBody(values, ur.GetFiles()); err != nil {
+			return nil, err
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, body)
 
 	if err != nil {
 		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Content-Type", contentType)
 	httpResp, err := sb.client.Do(httpReq)
 
 	return httpResp, err
 }
 
+// multipartBody
+//
+// multipart/form-data body with the values and the files
+func multipartBody(values url.Values, files map[string]InputFile) (io.Reader, string, error) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	for key, vals := range values {
+		for _, val := range vals {
+			if err := w.WriteField(key, val); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	for field, file := range files {
+		part, err := w.CreateFormFile(field, file.Name)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := io.Copy(part, file.Reader); err != nil {
+			return nil, "", fmt.Errorf("upload file '%s' error: '%w'", file.Name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf, w.FormDataContentType(), nil
+}
+
 func (sb SimpleBot) GetUpdates(ctx context.Context, req UpdatesRequest) (UpdateResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, sb.updateTimeout)
 	defer cancel()
@@ -138,6 +193,50 @@ func (sb SimpleBot) Send(ctx context.Context, req Request) (MessageResponse, err
 	return mr, err
 }
 
+// Call
+//
+// Send the request and decode the result into the result value, e.g. a slice or a bool
+func (sb SimpleBot) Call(ctx context.Context, req Request, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, sb.sendTimeout)
+	defer cancel()
+
+	httpResp, err := sb.sendRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	resp := struct {
+		Ok          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+		ErrorCode   int             `json:"error_code"`
+	}{}
+	if err := ParseJson(&resp, httpResp.Body); err != nil {
+		return err
+	}
+	if !resp.Ok {
+		return ErrStatus{ErrorCode: resp.ErrorCode, Description: resp.Description}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// Call
+//
+// Send the request with the bot and return the typed result.
+// The bot must implement Caller, SimpleBot does.
+func Call[T any](ctx context.Context, b Bot, req Request) (T, error) {
+	var result T
+	c, ok := b.(Caller)
+	if !ok {
+		return result, fmt.Errorf("bot %T doesn't implement Caller", b)
+	}
+	err := c.Call(ctx, req, &result)
+	return result, err
+}
+
 type SimplePoller struct {
 	bot           Bot
 	offset        int
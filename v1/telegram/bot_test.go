@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -87,6 +88,30 @@ func TestSimpleBot_sendRequest(t *testing.T) {
 	}
 }
 
+func TestSimpleBot_sendRequest_Upload(t *testing.T) {
+	tb := NewSimpleBot("***Token***", httpClientMock{})
+	req := SetChatPhoto{ChatId: -100, Photo: InputFile{Name: "photo.jpg", Reader: strings.NewReader("jpeg data")}}
+	resp, err := tb.sendRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("SimpleBot.sendRequest() error = %v", err)
+	}
+	httpReq := resp.Request
+	if err := httpReq.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("ParseMultipartForm() error = %v", err)
+	}
+	if got := httpReq.FormValue("chat_id"); got != "-100" {
+		t.Errorf("SimpleBot.sendRequest() chat_id = %v, want -100", got)
+	}
+	file, header, err := httpReq.FormFile("photo")
+	if err != nil {
+		t.Fatalf("FormFile() error = %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	if header.Filename != "photo.jpg" || string(data) != "jpeg data" {
+		t.Errorf("SimpleBot.sendRequest() file = %s %s, want photo.jpg jpeg data", header.Filename, data)
+	}
+}
+
 func TestSimpleBotSend(t *testing.T) {
 	tb := NewSimpleBot(
 		"***Token***",
@@ -133,6 +158,59 @@ func TestSimpleBotSend(t *testing.T) {
 	}
 }
 
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		err     error
+		want    []MessageId
+		wantErr bool
+	}{
+		{
+			name: "Result",
+			body: `{"ok": true, "result": [{"message_id": 10}, {"message_id": 11}]}`,
+			want: []MessageId{{MessageId: 10}, {MessageId: 11}},
+		},
+		{
+			name:    "Status error",
+			body:    `{"ok": false, "error_code": 400, "description": "Bad Request: message not found"}`,
+			wantErr: true,
+		},
+		{
+			name:    "HTTP error",
+			err:     errors.New("HTTP error"),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid result",
+			body:    `{"ok": true, "result": true}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := NewSimpleBot("***Token***", httpClientMock{body: tt.body, err: tt.err})
+			got, err := Call[[]MessageId](context.Background(), tb, ForwardMessages{ChatId: 1, FromChatId: 2, MessageIds: []int{1, 2}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Call() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Call() difference: %v", diff)
+			}
+		})
+	}
+
+	var status ErrStatus
+	tb := NewSimpleBot("***Token***", httpClientMock{body: `{"ok": false, "error_code": 403, "description": "Forbidden"}`})
+	if _, err := Call[bool](context.Background(), tb, DeleteMessage{ChatId: 1, MessageId: 1}); !errors.As(err, &status) || status.ErrorCode != 403 {
+		t.Errorf("Call() error = %v, want status error 403", err)
+	}
+	if _, err := Call[bool](context.Background(), &botMock{}, DeleteMessage{ChatId: 1, MessageId: 1}); err == nil {
+		t.Errorf("Call() error = %v, want error for bot without Caller", err)
+	}
+}
+
 func TestSimpleBotGetUpdates(t *testing.T) {
 	httpErr := errors.New("HTTP error")
 	data := []struct {
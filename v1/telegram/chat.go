@@ -0,0 +1,533 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// BanChatMember
+//
+// Ban the user in the group, supergroup or channel until UntilDate, forever if it is zero
+type BanChatMember struct {
+	ChatId         interface{} `json:"chat_id"`
+	UserId         int         `json:"user_id"`
+	UntilDate      int         `json:"until_date,omitempty"`
+	RevokeMessages bool        `json:"revoke_messages,omitempty"`
+}
+
+func (req BanChatMember) GetParams() (val url.Values, method string, err error) {
+	method = "banChatMember"
+	if val, err = chatMemberParams(req.ChatId, req.UserId); err != nil {
+		return nil, "", err
+	}
+	if req.UntilDate != 0 {
+		val.Add("until_date", strconv.Itoa(req.UntilDate))
+	}
+	if req.RevokeMessages {
+		val.Add("revoke_messages", strconv.FormatBool(req.RevokeMessages))
+	}
+	return
+}
+
+// UnbanChatMember
+//
+// Unban the user, with OnlyIfBanned a member of the chat isn't removed from it
+type UnbanChatMember struct {
+	ChatId       interface{} `json:"chat_id"`
+	UserId       int         `json:"user_id"`
+	OnlyIfBanned bool        `json:"only_if_banned,omitempty"`
+}
+
+func (req UnbanChatMember) GetParams() (val url.Values, method string, err error) {
+	method = "unbanChatMember"
+	if val, err = chatMemberParams(req.ChatId, req.UserId); err != nil {
+		return nil, "", err
+	}
+	if req.OnlyIfBanned {
+		val.Add("only_if_banned", strconv.FormatBool(req.OnlyIfBanned))
+	}
+	return
+}
+
+// RestrictChatMember
+//
+// Restrict the user in the supergroup to the permissions until UntilDate, forever if it is zero
+type RestrictChatMember struct {
+	ChatId                        interface{}     `json:"chat_id"`
+	UserId                        int             `json:"user_id"`
+	Permissions                   ChatPermissions `json:"permissions"`
+	UseIndependentChatPermissions bool            `json:"use_independent_chat_permissions,omitempty"`
+	UntilDate                     int             `json:"until_date,omitempty"`
+}
+
+func (req RestrictChatMember) GetParams() (val url.Values, method string, err error) {
+	method = "restrictChatMember"
+	if val, err = chatMemberParams(req.ChatId, req.UserId); err != nil {
+		return nil, "", err
+	}
+	if err := addJSON(val, "permissions", req.Permissions); err != nil {
+		return nil, "", err
+	}
+	if req.UseIndependentChatPermissions {
+		val.Add("use_independent_chat_permissions", strconv.FormatBool(req.UseIndependentChatPermissions))
+	}
+	if req.UntilDate != 0 {
+		val.Add("until_date", strconv.Itoa(req.UntilDate))
+	}
+	return
+}
+
+// PromoteChatMember
+//
+// Promote the user to an administrator with the rights, the user without rights is demoted
+type PromoteChatMember struct {
+	ChatId interface{}             `json:"chat_id"`
+	UserId int                     `json:"user_id"`
+	Rights ChatAdministratorRights `json:"rights"`
+}
+
+func (req PromoteChatMember) GetParams() (val url.Values, method string, err error) {
+	method = "promoteChatMember"
+	if val, err = chatMemberParams(req.ChatId, req.UserId); err != nil {
+		return nil, "", err
+	}
+	// The rights are separate params of the request
+	data, err := json.Marshal(req.Rights)
+	if err != nil {
+		return nil, "", err
+	}
+	rights := map[string]bool{}
+	if err := json.Unmarshal(data, &rights); err != nil {
+		return nil, "", err
+	}
+	for name, allowed := range rights {
+		val.Add(name, strconv.FormatBool(allowed))
+	}
+	return
+}
+
+type SetChatAdministratorCustomTitle struct {
+	ChatId      interface{} `json:"chat_id"`
+	UserId      int         `json:"user_id"`
+	CustomTitle string      `json:"custom_title"`
+}
+
+func (req SetChatAdministratorCustomTitle) GetParams() (val url.Values, method string, err error) {
+	method = "setChatAdministratorCustomTitle"
+	if val, err = chatMemberParams(req.ChatId, req.UserId); err != nil {
+		return nil, "", err
+	}
+	val.Add("custom_title", req.CustomTitle)
+	return
+}
+
+// BanChatSenderChat
+//
+// Ban the channel chat in the supergroup or channel, its owner can't send messages on behalf of any channel
+type BanChatSenderChat struct {
+	ChatId       interface{} `json:"chat_id"`
+	SenderChatId int         `json:"sender_chat_id"`
+}
+
+func (req BanChatSenderChat) GetParams() (val url.Values, method string, err error) {
+	method = "banChatSenderChat"
+	if val, err = senderChatParams(req.ChatId, req.SenderChatId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+type UnbanChatSenderChat struct {
+	ChatId       interface{} `json:"chat_id"`
+	SenderChatId int         `json:"sender_chat_id"`
+}
+
+func (req UnbanChatSenderChat) GetParams() (val url.Values, method string, err error) {
+	method = "unbanChatSenderChat"
+	if val, err = senderChatParams(req.ChatId, req.SenderChatId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+// SetChatPermissions
+//
+// Default permissions of the chat members
+type SetChatPermissions struct {
+	ChatId                        interface{}     `json:"chat_id"`
+	Permissions                   ChatPermissions `json:"permissions"`
+	UseIndependentChatPermissions bool            `json:"use_independent_chat_permissions,omitempty"`
+}
+
+func (req SetChatPermissions) GetParams() (val url.Values, method string, err error) {
+	method = "setChatPermissions"
+	if val, err = chatParams(req.ChatId); err != nil {
+		return nil, "", err
+	}
+	if err := addJSON(val, "permissions", req.Permissions); err != nil {
+		return nil, "", err
+	}
+	if req.UseIndependentChatPermissions {
+		val.Add("use_independent_chat_permissions", strconv.FormatBool(req.UseIndependentChatPermissions))
+	}
+	return
+}
+
+type SetChatTitle struct {
+	ChatId interface{} `json:"chat_id"`
+	Title  string      `json:"title"`
+}
+
+func (req SetChatTitle) GetParams() (val url.Values, method string, err error) {
+	method = "setChatTitle"
+	if req.Title == "" {
+		return nil, "", fmt.Errorf("required fields not defined, Title: %s", req.Title)
+	}
+	if val, err = chatParams(req.ChatId); err != nil {
+		return nil, "", err
+	}
+	val.Add("title", req.Title)
+	return
+}
+
+// SetChatDescription
+//
+// Change the description of the chat, the empty description removes it
+type SetChatDescription struct {
+	ChatId      interface{} `json:"chat_id"`
+	Description string      `json:"description"`
+}
+
+func (req SetChatDescription) GetParams() (val url.Values, method string, err error) {
+	method = "setChatDescription"
+	if val, err = chatParams(req.ChatId); err != nil {
+		return nil, "", err
+	}
+	val.Add("description", req.Description)
+	return
+}
+
+// SetChatPhoto
+//
+// Upload the new photo of the chat
+type SetChatPhoto struct {
+	ChatId interface{} `json:"chat_id"`
+	Photo  InputFile   `json:"-"`
+}
+
+func (req SetChatPhoto) GetParams() (val url.Values, method string, err error) {
+	method = "setChatPhoto"
+	if req.Photo.Reader == nil {
+		return nil, "", fmt.Errorf("required fields not defined, Photo: %v", req.Photo)
+	}
+	if val, err = chatParams(req.ChatId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+func (req SetChatPhoto) GetFiles() map[string]InputFile {
+	return map[string]InputFile{"photo": req.Photo}
+}
+
+type DeleteChatPhoto struct {
+	ChatId interface{} `json:"chat_id"`
+}
+
+func (req DeleteChatPhoto) GetParams() (val url.Values, method string, err error) {
+	method = "deleteChatPhoto"
+	if val, err = chatParams(req.ChatId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+type LeaveChat struct {
+	ChatId interface{} `json:"chat_id"`
+}
+
+func (req LeaveChat) GetParams() (val url.Values, method string, err error) {
+	method = "leaveChat"
+	if val, err = chatParams(req.ChatId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+func chatParams(chatId interface{}) (url.Values, error) {
+	if chatId == nil {
+		return nil, fmt.Errorf("required fields not defined, ChatId: %v", chatId)
+	}
+	return url.Values{"chat_id": {fmt.Sprint(chatId)}}, nil
+}
+
+func chatMemberParams(chatId interface{}, userId int) (url.Values, error) {
+	if chatId == nil || userId == 0 {
+		return nil, fmt.Errorf("required fields not defined, ChatId: %v, UserId: %d", chatId, userId)
+	}
+	return url.Values{"chat_id": {fmt.Sprint(chatId)}, "user_id": {strconv.Itoa(userId)}}, nil
+}
+
+func senderChatParams(chatId interface{}, senderChatId int) (url.Values, error) {
+	if chatId == nil || senderChatId == 0 {
+		return nil, fmt.Errorf("required fields not defined, ChatId: %v, SenderChatId: %d", chatId, senderChatId)
+	}
+	return url.Values{"chat_id": {fmt.Sprint(chatId)}, "sender_chat_id": {strconv.Itoa(senderChatId)}}, nil
+}
+
+type GetChat struct {
+	ChatId interface{} `json:"chat_id"`
+}
+
+func (req GetChat) GetParams() (val url.Values, method string, err error) {
+	method = "getChat"
+	if val, err = chatParams(req.ChatId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+// Call
+//
+// Full information about the chat
+func (req GetChat) Call(ctx context.Context, b Bot) (ChatFullInfo, error) {
+	chat, err := Call[ChatFullInfo](ctx, b, req)
+	if err != nil || chat.PinnedMessage == nil {
+		return chat, err
+	}
+	pinned, err := normalizeMessage(*chat.PinnedMessage)
+	if err != nil {
+		return ChatFullInfo{}, err
+	}
+	chat.PinnedMessage = &pinned
+	return chat, nil
+}
+
+type GetChatMember struct {
+	ChatId interface{} `json:"chat_id"`
+	UserId int         `json:"user_id"`
+}
+
+func (req GetChatMember) GetParams() (val url.Values, method string, err error) {
+	method = "getChatMember"
+	if val, err = chatMemberParams(req.ChatId, req.UserId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+// Call
+//
+// Member of the chat
+func (req GetChatMember) Call(ctx context.Context, b Bot) (ChatMember, error) {
+	m, err := Call[ChatMemberUnion](ctx, b, req)
+	return m.ChatMember, err
+}
+
+// GetChatAdministrators
+//
+// Administrators of the chat except the other bots
+type GetChatAdministrators struct {
+	ChatId interface{} `json:"chat_id"`
+}
+
+func (req GetChatAdministrators) GetParams() (val url.Values, method string, err error) {
+	method = "getChatAdministrators"
+	if val, err = chatParams(req.ChatId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+// Call
+//
+// Administrators of the chat
+func (req GetChatAdministrators) Call(ctx context.Context, b Bot) ([]ChatMember, error) {
+	list, err := Call[[]ChatMemberUnion](ctx, b, req)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ChatMember, len(list))
+	for i, m := range list {
+		members[i] = m.ChatMember
+	}
+	return members, nil
+}
+
+type GetChatMemberCount struct {
+	ChatId interface{} `json:"chat_id"`
+}
+
+func (req GetChatMemberCount) GetParams() (val url.Values, method string, err error) {
+	method = "getChatMemberCount"
+	if val, err = chatParams(req.ChatId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+// Call
+//
+// Number of the chat members
+func (req GetChatMemberCount) Call(ctx context.Context, b Bot) (int, error) {
+	return Call[int](ctx, b, req)
+}
+
+// CreateChatInviteLink
+//
+// Additional invite link of the chat. MemberLimit can't be set for a link that CreatesJoinRequest.
+type CreateChatInviteLink struct {
+	ChatId             interface{} `json:"chat_id"`
+	Name               string      `json:"name,omitempty"`
+	ExpireDate         int         `json:"expire_date,omitempty"`
+	MemberLimit        int         `json:"member_limit,omitempty"`
+	CreatesJoinRequest bool        `json:"creates_join_request,omitempty"`
+}
+
+func (req CreateChatInviteLink) GetParams() (val url.Values, method string, err error) {
+	method = "createChatInviteLink"
+	if val, err = chatParams(req.ChatId); err != nil {
+		return nil, "", err
+	}
+	if err := inviteLinkParams(val, req.Name, req.ExpireDate, req.MemberLimit, req.CreatesJoinRequest); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+// Call
+//
+// Created invite link
+func (req CreateChatInviteLink) Call(ctx context.Context, b Bot) (ChatInviteLink, error) {
+	return Call[ChatInviteLink](ctx, b, req)
+}
+
+// EditChatInviteLink
+//
+// Edit the invite link created by the bot, the omitted options are reset
+type EditChatInviteLink struct {
+	ChatId             interface{} `json:"chat_id"`
+	InviteLink         string      `json:"invite_link"`
+	Name               string      `json:"name,omitempty"`
+	ExpireDate         int         `json:"expire_date,omitempty"`
+	MemberLimit        int         `json:"member_limit,omitempty"`
+	CreatesJoinRequest bool        `json:"creates_join_request,omitempty"`
+}
+
+func (req EditChatInviteLink) GetParams() (val url.Values, method string, err error) {
+	method = "editChatInviteLink"
+	if val, err = chatInviteLinkParams(req.ChatId, req.InviteLink); err != nil {
+		return nil, "", err
+	}
+	if err := inviteLinkParams(val, req.Name, req.ExpireDate, req.MemberLimit, req.CreatesJoinRequest); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+// Call
+//
+// Edited invite link
+func (req EditChatInviteLink) Call(ctx context.Context, b Bot) (ChatInviteLink, error) {
+	return Call[ChatInviteLink](ctx, b, req)
+}
+
+// RevokeChatInviteLink
+//
+// Revoke the invite link, a revoked primary link is replaced with a new one
+type RevokeChatInviteLink struct {
+	ChatId     interface{} `json:"chat_id"`
+	InviteLink string      `json:"invite_link"`
+}
+
+func (req RevokeChatInviteLink) GetParams() (val url.Values, method string, err error) {
+	method = "revokeChatInviteLink"
+	if val, err = chatInviteLinkParams(req.ChatId, req.InviteLink); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+// Call
+//
+// Revoked invite link
+func (req RevokeChatInviteLink) Call(ctx context.Context, b Bot) (ChatInviteLink, error) {
+	return Call[ChatInviteLink](ctx, b, req)
+}
+
+// ExportChatInviteLink
+//
+// Replace the primary invite link of the chat with a new one
+type ExportChatInviteLink struct {
+	ChatId interface{} `json:"chat_id"`
+}
+
+func (req ExportChatInviteLink) GetParams() (val url.Values, method string, err error) {
+	method = "exportChatInviteLink"
+	if val, err = chatParams(req.ChatId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+// Call
+//
+// New primary invite link
+func (req ExportChatInviteLink) Call(ctx context.Context, b Bot) (string, error) {
+	return Call[string](ctx, b, req)
+}
+
+type ApproveChatJoinRequest struct {
+	ChatId interface{} `json:"chat_id"`
+	UserId int         `json:"user_id"`
+}
+
+func (req ApproveChatJoinRequest) GetParams() (val url.Values, method string, err error) {
+	method = "approveChatJoinRequest"
+	if val, err = chatMemberParams(req.ChatId, req.UserId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+type DeclineChatJoinRequest struct {
+	ChatId interface{} `json:"chat_id"`
+	UserId int         `json:"user_id"`
+}
+
+func (req DeclineChatJoinRequest) GetParams() (val url.Values, method string, err error) {
+	method = "declineChatJoinRequest"
+	if val, err = chatMemberParams(req.ChatId, req.UserId); err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+func chatInviteLinkParams(chatId interface{}, inviteLink string) (url.Values, error) {
+	if chatId == nil || inviteLink == "" {
+		return nil, fmt.Errorf("required fields not defined, ChatId: %v, InviteLink: %s", chatId, inviteLink)
+	}
+	return url.Values{"chat_id": {fmt.Sprint(chatId)}, "invite_link": {inviteLink}}, nil
+}
+
+func inviteLinkParams(val url.Values, name string, expireDate int, memberLimit int, createsJoinRequest bool) error {
+	if createsJoinRequest && memberLimit > 0 {
+		return fmt.Errorf("member limit %d can't be set for the link creating join requests", memberLimit)
+	}
+	if name != "" {
+		val.Add("name", name)
+	}
+	if expireDate != 0 {
+		val.Add("expire_date", strconv.Itoa(expireDate))
+	}
+	if memberLimit != 0 {
+		val.Add("member_limit", strconv.Itoa(memberLimit))
+	}
+	if createsJoinRequest {
+		val.Add("creates_join_request", strconv.FormatBool(createsJoinRequest))
+	}
+	return nil
+}
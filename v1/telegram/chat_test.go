@@ -0,0 +1,312 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChatAdministration_GetParams(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    Request
+		wantVal    url.Values
+		wantMethod string
+		wantErr    bool
+	}{
+		{
+			name:       "BanChatMember",
+			request:    BanChatMember{ChatId: -100, UserId: 1, UntilDate: 1700000000, RevokeMessages: true},
+			wantVal:    url.Values{"chat_id": {"-100"}, "user_id": {"1"}, "until_date": {"1700000000"}, "revoke_messages": {"true"}},
+			wantMethod: "banChatMember",
+		},
+		{name: "BanChatMember without user", request: BanChatMember{ChatId: -100}, wantErr: true},
+		{
+			name:       "UnbanChatMember",
+			request:    UnbanChatMember{ChatId: "@group", UserId: 1, OnlyIfBanned: true},
+			wantVal:    url.Values{"chat_id": {"@group"}, "user_id": {"1"}, "only_if_banned": {"true"}},
+			wantMethod: "unbanChatMember",
+		},
+		{
+			name: "RestrictChatMember",
+			request: RestrictChatMember{ChatId: -100, UserId: 1, UntilDate: 1700000000, UseIndependentChatPermissions: true,
+				Permissions: ChatPermissions{CanSendMessages: true, CanSendPhotos: true}},
+			wantVal: url.Values{"chat_id": {"-100"}, "user_id": {"1"}, "until_date": {"1700000000"},
+				"permissions":                      {`{"can_send_messages":true,"can_send_photos":true}`},
+				"use_independent_chat_permissions": {"true"}},
+			wantMethod: "restrictChatMember",
+		},
+		{
+			name:       "RestrictChatMember mute",
+			request:    RestrictChatMember{ChatId: -100, UserId: 1},
+			wantVal:    url.Values{"chat_id": {"-100"}, "user_id": {"1"}, "permissions": {`{}`}},
+			wantMethod: "restrictChatMember",
+		},
+		{
+			name: "PromoteChatMember",
+			request: PromoteChatMember{ChatId: -100, UserId: 1,
+				Rights: ChatAdministratorRights{CanDeleteMessages: true, CanRestrictMembers: true, CanManageTopics: true}},
+			wantVal: url.Values{"chat_id": {"-100"}, "user_id": {"1"}, "can_delete_messages": {"true"},
+				"can_restrict_members": {"true"}, "can_manage_topics": {"true"}},
+			wantMethod: "promoteChatMember",
+		},
+		{
+			name:       "SetChatAdministratorCustomTitle",
+			request:    SetChatAdministratorCustomTitle{ChatId: -100, UserId: 1, CustomTitle: "Moderator"},
+			wantVal:    url.Values{"chat_id": {"-100"}, "user_id": {"1"}, "custom_title": {"Moderator"}},
+			wantMethod: "setChatAdministratorCustomTitle",
+		},
+		{
+			name:       "BanChatSenderChat",
+			request:    BanChatSenderChat{ChatId: -100, SenderChatId: -200},
+			wantVal:    url.Values{"chat_id": {"-100"}, "sender_chat_id": {"-200"}},
+			wantMethod: "banChatSenderChat",
+		},
+		{name: "BanChatSenderChat without sender", request: BanChatSenderChat{ChatId: -100}, wantErr: true},
+		{
+			name:       "UnbanChatSenderChat",
+			request:    UnbanChatSenderChat{ChatId: -100, SenderChatId: -200},
+			wantVal:    url.Values{"chat_id": {"-100"}, "sender_chat_id": {"-200"}},
+			wantMethod: "unbanChatSenderChat",
+		},
+		{
+			name:       "SetChatPermissions",
+			request:    SetChatPermissions{ChatId: -100, Permissions: ChatPermissions{CanSendMessages: true, CanSendPolls: true}},
+			wantVal:    url.Values{"chat_id": {"-100"}, "permissions": {`{"can_send_messages":true,"can_send_polls":true}`}},
+			wantMethod: "setChatPermissions",
+		},
+		{
+			name:       "SetChatTitle",
+			request:    SetChatTitle{ChatId: -100, Title: "Group"},
+			wantVal:    url.Values{"chat_id": {"-100"}, "title": {"Group"}},
+			wantMethod: "setChatTitle",
+		},
+		{name: "SetChatTitle without title", request: SetChatTitle{ChatId: -100}, wantErr: true},
+		{
+			name:       "SetChatDescription",
+			request:    SetChatDescription{ChatId: -100},
+			wantVal:    url.Values{"chat_id": {"-100"}, "description": {""}},
+			wantMethod: "setChatDescription",
+		},
+		{
+			name:       "SetChatPhoto",
+			request:    SetChatPhoto{ChatId: -100, Photo: InputFile{Name: "photo.jpg", Reader: strings.NewReader("jpeg")}},
+			wantVal:    url.Values{"chat_id": {"-100"}},
+			wantMethod: "setChatPhoto",
+		},
+		{name: "SetChatPhoto without photo", request: SetChatPhoto{ChatId: -100}, wantErr: true},
+		{
+			name:       "DeleteChatPhoto",
+			request:    DeleteChatPhoto{ChatId: -100},
+			wantVal:    url.Values{"chat_id": {"-100"}},
+			wantMethod: "deleteChatPhoto",
+		},
+		{
+			name:       "LeaveChat",
+			request:    LeaveChat{ChatId: "@group"},
+			wantVal:    url.Values{"chat_id": {"@group"}},
+			wantMethod: "leaveChat",
+		},
+		{name: "LeaveChat without chat", request: LeaveChat{}, wantErr: true},
+		{
+			name:       "GetChat",
+			request:    GetChat{ChatId: "@group"},
+			wantVal:    url.Values{"chat_id": {"@group"}},
+			wantMethod: "getChat",
+		},
+		{
+			name:       "GetChatMember",
+			request:    GetChatMember{ChatId: -100, UserId: 1},
+			wantVal:    url.Values{"chat_id": {"-100"}, "user_id": {"1"}},
+			wantMethod: "getChatMember",
+		},
+		{name: "GetChatMember without user", request: GetChatMember{ChatId: -100}, wantErr: true},
+		{
+			name:       "GetChatAdministrators",
+			request:    GetChatAdministrators{ChatId: -100},
+			wantVal:    url.Values{"chat_id": {"-100"}},
+			wantMethod: "getChatAdministrators",
+		},
+		{
+			name:       "GetChatMemberCount",
+			request:    GetChatMemberCount{ChatId: -100},
+			wantVal:    url.Values{"chat_id": {"-100"}},
+			wantMethod: "getChatMemberCount",
+		},
+		{
+			name:    "CreateChatInviteLink",
+			request: CreateChatInviteLink{ChatId: -100, Name: "Questionnaire", ExpireDate: 1700000000, CreatesJoinRequest: true},
+			wantVal: url.Values{"chat_id": {"-100"}, "name": {"Questionnaire"}, "expire_date": {"1700000000"},
+				"creates_join_request": {"true"}},
+			wantMethod: "createChatInviteLink",
+		},
+		{
+			name:       "CreateChatInviteLink with member limit",
+			request:    CreateChatInviteLink{ChatId: -100, MemberLimit: 10},
+			wantVal:    url.Values{"chat_id": {"-100"}, "member_limit": {"10"}},
+			wantMethod: "createChatInviteLink",
+		},
+		{
+			name:    "CreateChatInviteLink member limit with join request",
+			request: CreateChatInviteLink{ChatId: -100, MemberLimit: 10, CreatesJoinRequest: true},
+			wantErr: true,
+		},
+		{name: "CreateChatInviteLink without chat", request: CreateChatInviteLink{}, wantErr: true},
+		{
+			name:       "EditChatInviteLink",
+			request:    EditChatInviteLink{ChatId: -100, InviteLink: "https://t.me/+AbCd", Name: "Closed", MemberLimit: 1},
+			wantVal:    url.Values{"chat_id": {"-100"}, "invite_link": {"https://t.me/+AbCd"}, "name": {"Closed"}, "member_limit": {"1"}},
+			wantMethod: "editChatInviteLink",
+		},
+		{name: "EditChatInviteLink without link", request: EditChatInviteLink{ChatId: -100}, wantErr: true},
+		{
+			name:       "RevokeChatInviteLink",
+			request:    RevokeChatInviteLink{ChatId: "@group", InviteLink: "https://t.me/+AbCd"},
+			wantVal:    url.Values{"chat_id": {"@group"}, "invite_link": {"https://t.me/+AbCd"}},
+			wantMethod: "revokeChatInviteLink",
+		},
+		{name: "RevokeChatInviteLink without link", request: RevokeChatInviteLink{ChatId: -100}, wantErr: true},
+		{
+			name:       "ExportChatInviteLink",
+			request:    ExportChatInviteLink{ChatId: -100},
+			wantVal:    url.Values{"chat_id": {"-100"}},
+			wantMethod: "exportChatInviteLink",
+		},
+		{
+			name:       "ApproveChatJoinRequest",
+			request:    ApproveChatJoinRequest{ChatId: -100, UserId: 1},
+			wantVal:    url.Values{"chat_id": {"-100"}, "user_id": {"1"}},
+			wantMethod: "approveChatJoinRequest",
+		},
+		{name: "ApproveChatJoinRequest without user", request: ApproveChatJoinRequest{ChatId: -100}, wantErr: true},
+		{
+			name:       "DeclineChatJoinRequest",
+			request:    DeclineChatJoinRequest{ChatId: -100, UserId: 1},
+			wantVal:    url.Values{"chat_id": {"-100"}, "user_id": {"1"}},
+			wantMethod: "declineChatJoinRequest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotVal, gotMethod, err := tt.request.GetParams()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%T.GetParams() error = %v, wantErr %v", tt.request, err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.wantVal, gotVal); diff != "" {
+				t.Errorf("%T.GetParams() difference %v", tt.request, diff)
+			}
+			if gotMethod != tt.wantMethod {
+				t.Errorf("%T.GetParams() gotMethod = %v, want %v", tt.request, gotMethod, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestChatPermissions_JSON(t *testing.T) {
+	data := `{"can_send_messages":true,"can_send_audios":true,"can_send_documents":true,"can_send_photos":true,` +
+		`"can_send_videos":true,"can_send_video_notes":true,"can_send_voice_notes":true,"can_send_polls":true,` +
+		`"can_send_other_messages":true,"can_add_web_page_previews":true,"can_change_info":true,` +
+		`"can_invite_users":true,"can_pin_messages":true,"can_manage_topics":true}`
+	var perms ChatPermissions
+	if err := json.Unmarshal([]byte(data), &perms); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	got, err := json.Marshal(perms)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(got) != data {
+		t.Errorf("json.Marshal() = %s, want %s", got, data)
+	}
+}
+
+func TestChatQueries_Call(t *testing.T) {
+	cm := &callerMock{results: map[string]string{
+		"getChat": `{"id": -100, "type": "supergroup", "title": "Group", "join_by_request": true,
+			"permissions": {"can_send_messages": true},
+			"pinned_message": {"message_id": 5, "chat": {"id": -100, "type": "supergroup"}, "text": "Rules"}}`,
+		"getChatMember":         `{"status": "left", "user": {"id": 3}}`,
+		"getChatAdministrators": testAdministrators,
+		"getChatMemberCount":    `42`,
+	}}
+	ctx := context.Background()
+
+	chat, err := GetChat{ChatId: -100}.Call(ctx, cm)
+	wantChat := ChatFullInfo{Id: -100, Type: "supergroup", Title: "Group", JoinByRequest: true,
+		Permissions:   ChatPermissions{CanSendMessages: true},
+		PinnedMessage: &Message{MessageId: 5, Chat: Chat{Id: -100, Type: "supergroup"}, Text: "Rules"}}
+	if err != nil {
+		t.Fatalf("GetChat.Call() error = %v", err)
+	}
+	if diff := cmp.Diff(wantChat, chat); diff != "" {
+		t.Errorf("GetChat.Call() difference: %v", diff)
+	}
+
+	member, err := GetChatMember{ChatId: -100, UserId: 3}.Call(ctx, cm)
+	if err != nil || member.MemberStatus() != ChatMemberStatusLeft || member.MemberUser().Id != 3 {
+		t.Errorf("GetChatMember.Call() = %v, error = %v", member, err)
+	}
+
+	admins, err := GetChatAdministrators{ChatId: -100}.Call(ctx, cm)
+	if err != nil || len(admins) != 2 {
+		t.Fatalf("GetChatAdministrators.Call() = %v, error = %v", admins, err)
+	}
+	if admin, ok := admins[1].(ChatMemberAdministrator); !ok || admin.CustomTitle != "Moderator" || !admin.CanRestrictMembers {
+		t.Errorf("GetChatAdministrators.Call() administrator = %v", admins[1])
+	}
+
+	count, err := GetChatMemberCount{ChatId: -100}.Call(ctx, cm)
+	if err != nil || count != 42 {
+		t.Errorf("GetChatMemberCount.Call() = %v, error = %v", count, err)
+	}
+}
+
+func TestChatInviteLinks_Call(t *testing.T) {
+	link := `{"invite_link": "https://t.me/+AbCd", "creator": {"id": 1, "is_bot": true, "first_name": "Bot"},
+		"creates_join_request": true, "is_primary": false, "is_revoked": %v, "name": "Questionnaire"}`
+	cm := &callerMock{results: map[string]string{
+		"createChatInviteLink": fmt.Sprintf(link, false),
+		"editChatInviteLink":   fmt.Sprintf(link, false),
+		"revokeChatInviteLink": fmt.Sprintf(link, true),
+		"exportChatInviteLink": `"https://t.me/+EfGh"`,
+	}}
+	ctx := context.Background()
+	want := ChatInviteLink{InviteLink: "https://t.me/+AbCd", Creator: User{Id: 1, IsBot: true, FirstName: "Bot"},
+		CreatesJoinRequest: true, Name: "Questionnaire"}
+
+	got, err := CreateChatInviteLink{ChatId: -100, Name: "Questionnaire", CreatesJoinRequest: true}.Call(ctx, cm)
+	if err != nil {
+		t.Fatalf("CreateChatInviteLink.Call() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CreateChatInviteLink.Call() difference: %v", diff)
+	}
+
+	got, err = EditChatInviteLink{ChatId: -100, InviteLink: want.InviteLink, Name: "Questionnaire", CreatesJoinRequest: true}.Call(ctx, cm)
+	if err != nil {
+		t.Fatalf("EditChatInviteLink.Call() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("EditChatInviteLink.Call() difference: %v", diff)
+	}
+
+	want.IsRevoked = true
+	got, err = RevokeChatInviteLink{ChatId: -100, InviteLink: want.InviteLink}.Call(ctx, cm)
+	if err != nil {
+		t.Fatalf("RevokeChatInviteLink.Call() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RevokeChatInviteLink.Call() difference: %v", diff)
+	}
+
+	primary, err := ExportChatInviteLink{ChatId: -100}.Call(ctx, cm)
+	if err != nil || primary != "https://t.me/+EfGh" {
+		t.Errorf("ExportChatInviteLink.Call() = %v, error = %v", primary, err)
+	}
+}
@@ -57,20 +57,130 @@ type Chat struct {
 	Location              ChatLocation    `json:"location"`
 }
 
+// ChatAdministratorRights
+//
+// Rights of a chat administrator, the omitted rights are denied
+type ChatAdministratorRights struct {
+	IsAnonymous         bool `json:"is_anonymous,omitempty"`
+	CanManageChat       bool `json:"can_manage_chat,omitempty"`
+	CanDeleteMessages   bool `json:"can_delete_messages,omitempty"`
+	CanManageVideoChats bool `json:"can_manage_video_chats,omitempty"`
+	CanRestrictMembers  bool `json:"can_restrict_members,omitempty"`
+	CanPromoteMembers   bool `json:"can_promote_members,omitempty"`
+	CanChangeInfo       bool `json:"can_change_info,omitempty"`
+	CanInviteUsers      bool `json:"can_invite_users,omitempty"`
+	CanPostStories      bool `json:"can_post_stories,omitempty"`
+	CanEditStories      bool `json:"can_edit_stories,omitempty"`
+	CanDeleteStories    bool `json:"can_delete_stories,omitempty"`
+	CanPostMessages     bool `json:"can_post_messages,omitempty"`
+	CanEditMessages     bool `json:"can_edit_messages,omitempty"`
+	CanPinMessages      bool `json:"can_pin_messages,omitempty"`
+	CanManageTopics     bool `json:"can_manage_topics,omitempty"`
+}
+
+// ChatFullInfo
+//
+// Full information about a chat, the result of GetChat
+type ChatFullInfo struct {
+	Id                                 int             `json:"id"`
+	Type                               string          `json:"type"`
+	Title                              string          `json:"title,omitempty"`
+	Username                           string          `json:"username,omitempty"`
+	FirstName                          string          `json:"first_name,omitempty"`
+	LastName                           string          `json:"last_name,omitempty"`
+	IsForum                            bool            `json:"is_forum,omitempty"`
+	AccentColorId                      int             `json:"accent_color_id"`
+	MaxReactionCount                   int             `json:"max_reaction_count"`
+	Photo                              *ChatPhoto      `json:"photo,omitempty"`
+	ActiveUsernames                    []string        `json:"active_usernames,omitempty"`
+	Bio                                string          `json:"bio,omitempty"`
+	HasPrivateForwards                 bool            `json:"has_private_forwards,omitempty"`
+	HasRestrictedVoiceAndVideoMessages bool            `json:"has_restricted_voice_and_video_messages,omitempty"`
+	JoinToSendMessages                 bool            `json:"join_to_send_messages,omitempty"`
+	JoinByRequest                      bool            `json:"join_by_request,omitempty"`
+	Description                        string          `json:"description,omitempty"`
+	InviteLink                         string          `json:"invite_link,omitempty"`
+	PinnedMessage                      *Message        `json:"pinned_message,omitempty"`
+	Permissions                        ChatPermissions `json:"permissions"`
+	SlowModeDelay                      int             `json:"slow_mode_delay,omitempty"`
+	UnrestrictBoostCount               int             `json:"unrestrict_boost_count,omitempty"`
+	MessageAutoDeleteTime              int             `json:"message_auto_delete_time,omitempty"`
+	HasAggressiveAntiSpamEnabled       bool            `json:"has_aggressive_anti_spam_enabled,omitempty"`
+	HasHiddenMembers                   bool            `json:"has_hidden_members,omitempty"`
+	HasProtectedContent                bool            `json:"has_protected_content,omitempty"`
+	HasVisibleHistory                  bool            `json:"has_visible_history,omitempty"`
+	StickerSetName                     string          `json:"sticker_set_name,omitempty"`
+	CanSetStickerSet                   bool            `json:"can_set_sticker_set,omitempty"`
+	CustomEmojiStickerSetName          string          `json:"custom_emoji_sticker_set_name,omitempty"`
+	LinkedChatId                       int             `json:"linked_chat_id,omitempty"`
+	Location                           *ChatLocation   `json:"location,omitempty"`
+}
+
+// ChatInviteLink
+//
+// Invite link of a chat, the links of other administrators are shown truncated
+type ChatInviteLink struct {
+	InviteLink              string `json:"invite_link"`
+	Creator                 User   `json:"creator"`
+	CreatesJoinRequest      bool   `json:"creates_join_request"`
+	IsPrimary               bool   `json:"is_primary"`
+	IsRevoked               bool   `json:"is_revoked"`
+	Name                    string `json:"name,omitempty"`
+	ExpireDate              int    `json:"expire_date,omitempty"`
+	MemberLimit             int    `json:"member_limit,omitempty"`
+	PendingJoinRequestCount int    `json:"pending_join_request_count,omitempty"`
+}
+
+// ChatJoinRequest
+//
+// Request to join the chat. The bot can message the user by UserChatId
+// until the request is approved or declined.
+type ChatJoinRequest struct {
+	Chat       Chat            `json:"chat"`
+	From       User            `json:"from"`
+	UserChatId int             `json:"user_chat_id"`
+	Date       int             `json:"date"`
+	Bio        string          `json:"bio,omitempty"`
+	InviteLink *ChatInviteLink `json:"invite_link,omitempty"`
+}
+
+// Approve
+//
+// Approve the request, the user joins the chat
+func (jr ChatJoinRequest) Approve(ctx context.Context, b Bot) (MessageResponse, error) {
+	return b.Send(ctx, ApproveChatJoinRequest{ChatId: jr.Chat.Id, UserId: jr.From.Id})
+}
+
+// Decline
+//
+// Decline the request
+func (jr ChatJoinRequest) Decline(ctx context.Context, b Bot) (MessageResponse, error) {
+	return b.Send(ctx, DeclineChatJoinRequest{ChatId: jr.Chat.Id, UserId: jr.From.Id})
+}
+
 type ChatLocation struct {
 	Location Location `json:"location"`
 	Address  string   `json:"address"`
 }
 
+// ChatPermissions
+//
+// Actions allowed to the chat members, the omitted permissions are denied
 type ChatPermissions struct {
-	CanSendMessages       bool `json:"can_send_messages"`
-	CanSendMediaMessages  bool `json:"can_send_media_messages"`
-	CanSendPolls          bool `json:"can_send_polls"`
-	CanSendOtherMessages  bool `json:"can_send_other_messages"`
-	CanAddWebPagePreviews bool `json:"can_add_web_page_previews"`
-	CanChangeInfo         bool `json:"can_change_info"`
-	CanInviteUsers        bool `json:"can_invite_users"`
-	CanPinMessages        bool `json:"can_pin_messages"`
+	CanSendMessages       bool `json:"can_send_messages,omitempty"`
+	CanSendAudios         bool `json:"can_send_audios,omitempty"`
+	CanSendDocuments      bool `json:"can_send_documents,omitempty"`
+	CanSendPhotos         bool `json:"can_send_photos,omitempty"`
+	CanSendVideos         bool `json:"can_send_videos,omitempty"`
+	CanSendVideoNotes     bool `json:"can_send_video_notes,omitempty"`
+	CanSendVoiceNotes     bool `json:"can_send_voice_notes,omitempty"`
+	CanSendPolls          bool `json:"can_send_polls,omitempty"`
+	CanSendOtherMessages  bool `json:"can_send_other_messages,omitempty"`
+	CanAddWebPagePreviews bool `json:"can_add_web_page_previews,omitempty"`
+	CanChangeInfo         bool `json:"can_change_info,omitempty"`
+	CanInviteUsers        bool `json:"can_invite_users,omitempty"`
+	CanPinMessages        bool `json:"can_pin_messages,omitempty"`
+	CanManageTopics       bool `json:"can_manage_topics,omitempty"`
 }
 
 type ChatPhoto struct {
@@ -85,14 +195,53 @@ type ChatShared struct {
 	ChatId    int `json:"chat_id"`
 }
 
+// ForceReply
+//
+// Markup showing the reply interface to the user, ForceReply must be true
+type ForceReply struct {
+	ForceReply            bool   `json:"force_reply"`
+	InputFieldPlaceholder string `json:"input_field_placeholder,omitempty"`
+	Selective             bool   `json:"selective,omitempty"`
+}
+
+func NewForceReply() ForceReply {
+	return ForceReply{ForceReply: true}
+}
+
+// KeyboardButton
+//
+// Button of a reply keyboard, the optional fields are sent only when they are set
 type KeyboardButton struct {
-	Text        string                    `json:"text"`
-	RequestChat KeyboardButtonRequestChat `json:"request_chat"`
+	Text            string                      `json:"text"`
+	RequestUsers    *KeyboardButtonRequestUsers `json:"request_users,omitempty"`
+	RequestChat     *KeyboardButtonRequestChat  `json:"request_chat,omitempty"`
+	RequestContact  bool                        `json:"request_contact,omitempty"`
+	RequestLocation bool                        `json:"request_location,omitempty"`
+	RequestPoll     *KeyboardButtonPollType     `json:"request_poll,omitempty"`
+	WebApp          *WebAppInfo                 `json:"web_app,omitempty"`
+}
+
+// KeyboardButtonPollType
+//
+// Type of the poll created by the button, "quiz", "regular" or empty for any type
+type KeyboardButtonPollType struct {
+	Type string `json:"type,omitempty"`
 }
 
 type KeyboardButtonRequestChat struct {
-	RequestId   int  `json:"request_id"`
-	BotIsMember bool `json:"bot_is_member"`
+	RequestId       int   `json:"request_id"`
+	ChatIsChannel   bool  `json:"chat_is_channel"`
+	ChatIsForum     *bool `json:"chat_is_forum,omitempty"`
+	ChatHasUsername *bool `json:"chat_has_username,omitempty"`
+	ChatIsCreated   *bool `json:"chat_is_created,omitempty"`
+	BotIsMember     bool  `json:"bot_is_member,omitempty"`
+}
+
+type KeyboardButtonRequestUsers struct {
+	RequestId     int   `json:"request_id"`
+	UserIsBot     *bool `json:"user_is_bot,omitempty"`
+	UserIsPremium *bool `json:"user_is_premium,omitempty"`
+	MaxQuantity   int   `json:"max_quantity,omitempty"`
 }
 
 type InlineKeyboardButton struct {
@@ -160,6 +309,20 @@ func (msg Message) DeleteMessage(ctx context.Context, b Bot) (MessageResponse, e
 	return b.Send(ctx, DeleteMessage{ChatId: msg.Chat.Id, MessageId: msg.MessageId})
 }
 
+// Forward
+//
+// Forward the message to the chat
+func (msg Message) Forward(ctx context.Context, b Bot, chatId interface{}) (MessageResponse, error) {
+	return b.Send(ctx, ForwardMessage{ChatId: chatId, FromChatId: msg.Chat.Id, MessageId: msg.MessageId})
+}
+
+// CopyTo
+//
+// Copy the message to the chat, only MessageId of the result is set
+func (msg Message) CopyTo(ctx context.Context, b Bot, chatId interface{}) (MessageResponse, error) {
+	return b.Send(ctx, CopyMessage{ChatId: chatId, FromChatId: msg.Chat.Id, MessageId: msg.MessageId})
+}
+
 // Edit
 //
 // Edit message with current Text and ReplyMarkup
@@ -228,17 +391,43 @@ type MessageEntity struct {
 	User     *User  `json:"user,omitempty"`
 	Language string `json:"language,omitempty"`
 }
+
+// MessageId
+//
+// Identifier of a sent message, the result of the copy requests
+type MessageId struct {
+	MessageId int `json:"message_id"`
+}
+
 type ReplyKeyboardMarkup struct {
-	Keyboard [][]KeyboardButton `json:"keyboard"`
+	Keyboard              [][]KeyboardButton `json:"keyboard"`
+	IsPersistent          bool               `json:"is_persistent,omitempty"`
+	ResizeKeyboard        bool               `json:"resize_keyboard,omitempty"`
+	OneTimeKeyboard       bool               `json:"one_time_keyboard,omitempty"`
+	InputFieldPlaceholder string             `json:"input_field_placeholder,omitempty"`
+	Selective             bool               `json:"selective,omitempty"`
+}
+
+// ReplyKeyboardRemove
+//
+// Markup removing the reply keyboard, RemoveKeyboard must be true
+type ReplyKeyboardRemove struct {
+	RemoveKeyboard bool `json:"remove_keyboard"`
+	Selective      bool `json:"selective,omitempty"`
+}
+
+func NewReplyKeyboardRemove() ReplyKeyboardRemove {
+	return ReplyKeyboardRemove{RemoveKeyboard: true}
 }
 
 type Update struct {
-	UpdateId          int           `json:"update_id"`
-	Message           Message       `json:"message"`
-	EditedMessage     Message       `json:"edited_message"`
-	ChannelPost       Message       `json:"channel_post"`
-	EditedChannelPost Message       `json:"edited_channel_post"`
-	CallbackQuery     CallbackQuery `json:"callback_query"`
+	UpdateId          int             `json:"update_id"`
+	Message           Message         `json:"message"`
+	EditedMessage     Message         `json:"edited_message"`
+	ChannelPost       Message         `json:"channel_post"`
+	EditedChannelPost Message         `json:"edited_channel_post"`
+	CallbackQuery     CallbackQuery   `json:"callback_query"`
+	ChatJoinRequest   ChatJoinRequest `json:"chat_join_request"`
 }
 
 type User struct {
@@ -252,3 +441,10 @@ type User struct {
 	CanReadAllGroupMessages bool   `json:"can_read_all_group_messages,omitempty"`
 	SupportsInlineQueries   bool   `json:"supports_inline_queries,omitempty"`
 }
+
+// WebAppInfo
+//
+// Web App launched by the button
+type WebAppInfo struct {
+	Url string `json:"url"`
+}
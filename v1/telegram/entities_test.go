@@ -2,6 +2,7 @@ package telegram
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -102,6 +103,30 @@ func TestMessage_DeleteMessage(t *testing.T) {
 	}
 }
 
+func TestChatJoinRequest_Approve(t *testing.T) {
+	jr := ChatJoinRequest{Chat: Chat{Id: -100}, From: User{Id: 7}, UserChatId: 7, Date: 1}
+	tests := []struct {
+		name string
+		call func(ctx context.Context, b Bot) (MessageResponse, error)
+		want Request
+	}{
+		{name: "Approve", call: jr.Approve, want: ApproveChatJoinRequest{ChatId: -100, UserId: 7}},
+		{name: "Decline", call: jr.Decline, want: DeclineChatJoinRequest{ChatId: -100, UserId: 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bm := botMock{}
+			if _, err := tt.call(context.Background(), &bm); err != nil {
+				t.Errorf("ChatJoinRequest.%s() error = %v, wantErr %v", tt.name, err, nil)
+				return
+			}
+			if diff := cmp.Diff(bm.request, tt.want); diff != "" {
+				t.Errorf("ChatJoinRequest.%s() difference: %v", tt.name, diff)
+			}
+		})
+	}
+}
+
 func TestMessage_Edit(t *testing.T) {
 	bm := botMock{}
 	text := "New text"
@@ -221,6 +246,32 @@ func TestMessage_SendText(t *testing.T) {
 	}
 }
 
+func TestMessage_Forward(t *testing.T) {
+	bm := botMock{}
+	want := ForwardMessage{ChatId: -100, FromChatId: 1, MessageId: 10}
+	_, err := Message{Chat: Chat{Id: 1}, MessageId: 10}.Forward(context.Background(), &bm, -100)
+	if err != nil {
+		t.Errorf("Message.Forward() error = %v, wantErr %v", err, nil)
+		return
+	}
+	if diff := cmp.Diff(bm.request, want); diff != "" {
+		t.Errorf("Message.Forward() difference: %v", diff)
+	}
+}
+
+func TestMessage_CopyTo(t *testing.T) {
+	bm := botMock{}
+	want := CopyMessage{ChatId: "@channel", FromChatId: 1, MessageId: 10}
+	_, err := Message{Chat: Chat{Id: 1}, MessageId: 10}.CopyTo(context.Background(), &bm, "@channel")
+	if err != nil {
+		t.Errorf("Message.CopyTo() error = %v, wantErr %v", err, nil)
+		return
+	}
+	if diff := cmp.Diff(bm.request, want); diff != "" {
+		t.Errorf("Message.CopyTo() difference: %v", diff)
+	}
+}
+
 func TestCallbackQuery_Answer(t *testing.T) {
 	bm := botMock{}
 	text := "Answer text"
@@ -234,3 +285,63 @@ func TestCallbackQuery_Answer(t *testing.T) {
 		t.Errorf("CallbackQuery.Answer() difference: %v", diff)
 	}
 }
+
+func TestReplyMarkup_JSON(t *testing.T) {
+	yes := true
+	tests := []struct {
+		name   string
+		markup interface{}
+		want   string
+	}{
+		{
+			name:   "Plain button",
+			markup: ReplyKeyboardMarkup{Keyboard: [][]KeyboardButton{{{Text: "Button"}}}},
+			want:   `{"keyboard":[[{"text":"Button"}]]}`,
+		},
+		{
+			name: "Keyboard options",
+			markup: ReplyKeyboardMarkup{
+				Keyboard:              [][]KeyboardButton{{{Text: "Button"}}},
+				IsPersistent:          true,
+				ResizeKeyboard:        true,
+				OneTimeKeyboard:       true,
+				InputFieldPlaceholder: "Choose",
+				Selective:             true,
+			},
+			want: `{"keyboard":[[{"text":"Button"}]],"is_persistent":true,"resize_keyboard":true,` +
+				`"one_time_keyboard":true,"input_field_placeholder":"Choose","selective":true}`,
+		},
+		{
+			name: "Request buttons",
+			markup: ReplyKeyboardMarkup{Keyboard: [][]KeyboardButton{{
+				{Text: "Contact", RequestContact: true},
+				{Text: "Location", RequestLocation: true},
+				{Text: "Poll", RequestPoll: &KeyboardButtonPollType{Type: "quiz"}},
+				{Text: "Users", RequestUsers: &KeyboardButtonRequestUsers{RequestId: 1, UserIsBot: &yes, MaxQuantity: 2}},
+				{Text: "Chat", RequestChat: &KeyboardButtonRequestChat{RequestId: 2, ChatIsForum: &yes}},
+				{Text: "App", WebApp: &WebAppInfo{Url: "https://example.com"}},
+			}}},
+			want: `{"keyboard":[[{"text":"Contact","request_contact":true},{"text":"Location","request_location":true},` +
+				`{"text":"Poll","request_poll":{"type":"quiz"}},` +
+				`{"text":"Users","request_users":{"request_id":1,"user_is_bot":true,"max_quantity":2}},` +
+				`{"text":"Chat","request_chat":{"request_id":2,"chat_is_channel":false,"chat_is_forum":true}},` +
+				`{"text":"App","web_app":{"url":"https://example.com"}}]]}`,
+		},
+		{name: "Remove keyboard", markup: NewReplyKeyboardRemove(), want: `{"remove_keyboard":true}`},
+		{name: "Force reply", markup: ForceReply{ForceReply: true, InputFieldPlaceholder: "Name", Selective: true},
+			want: `{"force_reply":true,"input_field_placeholder":"Name","selective":true}`},
+		{name: "New force reply", markup: NewForceReply(), want: `{"force_reply":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.markup)
+			if err != nil {
+				t.Errorf("json.Marshal() error = %v, wantErr %v", err, nil)
+				return
+			}
+			if diff := cmp.Diff(string(data), tt.want); diff != "" {
+				t.Errorf("json.Marshal() difference: %v", diff)
+			}
+		})
+	}
+}
@@ -9,6 +9,14 @@ type Bot interface {
 	Send(context.Context, Request) (MessageResponse, error)
 }
 
+// Caller
+//
+// Bot decoding the results of any type, the results of Send are decoded as Message.
+// Call and the Call methods of the requests, e.g. GetChat.Call, work with the bots implementing it.
+type Caller interface {
+	Call(ctx context.Context, req Request, result interface{}) error
+}
+
 type UpdateHandler interface {
 	Proceed(context.Context, Bot, ...Update) error
 }
@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alex13th/telebot/v1/fsm"
+)
+
+const (
+	MaxInlineKeyboardButtons  = 100
+	MaxInlineKeyboardRowWidth = 8
+)
+
+var (
+	ErrKeyboardLimit = errors.New("the keyboard exceeds the Telegram limits")
+	ErrInvalidButton = errors.New("the keyboard button is invalid")
+)
+
+// InlineKeyboardBuilder
+//
+// Fluent builder of InlineKeyboardMarkup. Buttons are added to the current row,
+// Row starts a new one and with Columns the rows are wrapped automatically.
+// Callback data of the button states is encoded by the Codec, by State.CallbackData
+// if the Codec is nil. The first error is returned by Build.
+type InlineKeyboardBuilder struct {
+	Columns int
+	Codec   fsm.Codec
+	rows    [][]InlineKeyboardButton
+	err     error
+}
+
+func NewInlineKeyboard() *InlineKeyboardBuilder {
+	return &InlineKeyboardBuilder{}
+}
+
+// Wrap
+//
+// Wrap the rows to n columns
+func (kb *InlineKeyboardBuilder) Wrap(n int) *InlineKeyboardBuilder {
+	kb.Columns = n
+	return kb
+}
+
+// Row
+//
+// Start a new row, an empty current row is reused
+func (kb *InlineKeyboardBuilder) Row() *InlineKeyboardBuilder {
+	if len(kb.rows) == 0 || len(kb.rows[len(kb.rows)-1]) > 0 {
+		kb.rows = append(kb.rows, []InlineKeyboardButton{})
+	}
+	return kb
+}
+
+// Button
+//
+// Add a callback button with the encoded state, the state without Separator
+// is encoded with the separator of the fsm.NewState
+func (kb *InlineKeyboardBuilder) Button(text string, st fsm.State) *InlineKeyboardBuilder {
+	if st.Separator == "" {
+		st.Separator = fsm.NewState().Separator
+	}
+	var data string
+	var err error
+	if kb.Codec != nil {
+		data, err = kb.Codec.Encode(st)
+	} else {
+		data, err = st.CallbackData()
+	}
+	if err != nil {
+		kb.fail(fmt.Errorf("button '%s' callback data error: '%w'", text, err))
+		return kb
+	}
+	return kb.Add(InlineKeyboardButton{Text: text, CallbackData: data})
+}
+
+// URL
+//
+// Add a button opening the url
+func (kb *InlineKeyboardBuilder) URL(text string, url string) *InlineKeyboardBuilder {
+	return kb.Add(InlineKeyboardButton{Text: text, Url: url})
+}
+
+// Add
+//
+// Add the button to the current row
+func (kb *InlineKeyboardBuilder) Add(btn InlineKeyboardButton) *InlineKeyboardBuilder {
+	if len(kb.rows) == 0 || (kb.Columns > 0 && len(kb.rows[len(kb.rows)-1]) >= kb.Columns) {
+		kb.rows = append(kb.rows, []InlineKeyboardButton{})
+	}
+	kb.rows[len(kb.rows)-1] = append(kb.rows[len(kb.rows)-1], btn)
+	return kb
+}
+
+// Build
+//
+// Keyboard markup validated against the Telegram limits
+func (kb *InlineKeyboardBuilder) Build() (InlineKeyboardMarkup, error) {
+	if kb.err != nil {
+		return InlineKeyboardMarkup{}, kb.err
+	}
+	rows := make([][]InlineKeyboardButton, 0, len(kb.rows))
+	count := 0
+	for i, row := range kb.rows {
+		if len(row) == 0 {
+			continue
+		}
+		if len(row) > MaxInlineKeyboardRowWidth {
+			return InlineKeyboardMarkup{}, fmt.Errorf("%w: row %d has %d buttons, max %d",
+				ErrKeyboardLimit, i, len(row), MaxInlineKeyboardRowWidth)
+		}
+		for _, btn := range row {
+			if err := validateInlineButton(btn); err != nil {
+				return InlineKeyboardMarkup{}, err
+			}
+		}
+		count += len(row)
+		rows = append(rows, row)
+	}
+	if count > MaxInlineKeyboardButtons {
+		return InlineKeyboardMarkup{}, fmt.Errorf("%w: %d buttons, max %d", ErrKeyboardLimit, count, MaxInlineKeyboardButtons)
+	}
+	return InlineKeyboardMarkup{InlineKeyboard: rows}, nil
+}
+
+func (kb *InlineKeyboardBuilder) fail(err error) {
+	if kb.err == nil {
+		kb.err = err
+	}
+}
+
+func validateInlineButton(btn InlineKeyboardButton) error {
+	if btn.Text == "" {
+		return fmt.Errorf("%w: empty text, button: %v", ErrInvalidButton, btn)
+	}
+	if btn.Url == "" && btn.CallbackData == "" && btn.SwitchInlineQuery == "" &&
+		btn.SwitchInlineQueryCurrentChat == "" && !btn.Pay {
+		return fmt.Errorf("%w: button '%s' has no action", ErrInvalidButton, btn.Text)
+	}
+	if err := fsm.ValidateCallbackData(btn.CallbackData); err != nil {
+		return fmt.Errorf("button '%s' callback data error: '%w'", btn.Text, err)
+	}
+	return nil
+}
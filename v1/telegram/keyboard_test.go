@@ -0,0 +1,132 @@
+package telegram
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alex13th/telebot/v1/fsm"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInlineKeyboardBuilder_Build(t *testing.T) {
+	st := fsm.State{Prefix: "menu", State: "main"}
+	tests := []struct {
+		name  string
+		build func(kb *InlineKeyboardBuilder)
+		want  [][]InlineKeyboardButton
+	}{
+		{
+			name: "Rows",
+			build: func(kb *InlineKeyboardBuilder) {
+				kb.Button("Open", fsm.State{Prefix: "menu", State: "main", Action: "open", Key: "key1"}).
+					URL("Site", "https://example.com").
+					Row().
+					Button("Main", st)
+			},
+			want: [][]InlineKeyboardButton{
+				{{Text: "Open", CallbackData: "menu_main_open_key1"}, {Text: "Site", Url: "https://example.com"}},
+				{{Text: "Main", CallbackData: "menu_main_main"}},
+			},
+		},
+		{
+			name: "Wrap",
+			build: func(kb *InlineKeyboardBuilder) {
+				kb.Wrap(2)
+				for i := 1; i <= 3; i++ {
+					kb.URL(strconv.Itoa(i), "https://example.com")
+				}
+				kb.Row().Row().URL("4", "https://example.com")
+			},
+			want: [][]InlineKeyboardButton{
+				{{Text: "1", Url: "https://example.com"}, {Text: "2", Url: "https://example.com"}},
+				{{Text: "3", Url: "https://example.com"}},
+				{{Text: "4", Url: "https://example.com"}},
+			},
+		},
+		{
+			name: "Codec",
+			build: func(kb *InlineKeyboardBuilder) {
+				kb.Codec = fsm.EscapeCodec{Separator: "_"}
+				kb.Button("Key", fsm.State{Prefix: "menu", State: "main", Key: "a_b"})
+			},
+			want: [][]InlineKeyboardButton{{{Text: "Key", CallbackData: `menu_main_main_a\_b`}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb := NewInlineKeyboard()
+			tt.build(kb)
+			got, err := kb.Build()
+			if err != nil {
+				t.Errorf("InlineKeyboardBuilder.Build() error = %v, wantErr %v", err, nil)
+				return
+			}
+			if diff := cmp.Diff(got, InlineKeyboardMarkup{InlineKeyboard: tt.want}); diff != "" {
+				t.Errorf("InlineKeyboardBuilder.Build() difference: %v", diff)
+			}
+		})
+	}
+}
+
+func TestInlineKeyboardBuilder_Limits(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(kb *InlineKeyboardBuilder)
+		wantErr error
+	}{
+		{
+			name: "Row width",
+			build: func(kb *InlineKeyboardBuilder) {
+				for i := 0; i <= MaxInlineKeyboardRowWidth; i++ {
+					kb.URL("Site", "https://example.com")
+				}
+			},
+			wantErr: ErrKeyboardLimit,
+		},
+		{
+			name: "Buttons",
+			build: func(kb *InlineKeyboardBuilder) {
+				kb.Wrap(5)
+				for i := 0; i <= MaxInlineKeyboardButtons; i++ {
+					kb.URL("Site", "https://example.com")
+				}
+			},
+			wantErr: ErrKeyboardLimit,
+		},
+		{
+			name: "Callback data length",
+			build: func(kb *InlineKeyboardBuilder) {
+				kb.Button("Long", fsm.State{Prefix: "menu", State: "main", Key: strings.Repeat("k", 64)})
+			},
+			wantErr: fsm.ErrCallbackDataTooLong,
+		},
+		{
+			name: "Raw callback data length",
+			build: func(kb *InlineKeyboardBuilder) {
+				kb.Add(InlineKeyboardButton{Text: "Long", CallbackData: strings.Repeat("d", 65)})
+			},
+			wantErr: fsm.ErrCallbackDataTooLong,
+		},
+		{
+			name:    "Empty text",
+			build:   func(kb *InlineKeyboardBuilder) { kb.URL("", "https://example.com") },
+			wantErr: ErrInvalidButton,
+		},
+		{
+			name:    "No action",
+			build:   func(kb *InlineKeyboardBuilder) { kb.Add(InlineKeyboardButton{Text: "Nothing"}) },
+			wantErr: ErrInvalidButton,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb := NewInlineKeyboard()
+			tt.build(kb)
+			if _, err := kb.Build(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("InlineKeyboardBuilder.Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	ChatMemberStatusCreator       = "creator"
+	ChatMemberStatusAdministrator = "administrator"
+	ChatMemberStatusMember        = "member"
+	ChatMemberStatusRestricted    = "restricted"
+	ChatMemberStatusLeft          = "left"
+	ChatMemberStatusKicked        = "kicked"
+)
+
+// ChatMember
+//
+// Member of a chat, one of ChatMemberOwner, ChatMemberAdministrator, ChatMemberMember,
+// ChatMemberRestricted, ChatMemberLeft and ChatMemberBanned by the status
+type ChatMember interface {
+	MemberStatus() string
+	MemberUser() User
+}
+
+// ChatMemberBase
+//
+// Status and user shared by all the chat members
+type ChatMemberBase struct {
+	Status string `json:"status"`
+	User   User   `json:"user"`
+}
+
+func (m ChatMemberBase) MemberStatus() string {
+	return m.Status
+}
+
+func (m ChatMemberBase) MemberUser() User {
+	return m.User
+}
+
+type ChatMemberOwner struct {
+	ChatMemberBase
+	IsAnonymous bool   `json:"is_anonymous"`
+	CustomTitle string `json:"custom_title,omitempty"`
+}
+
+type ChatMemberAdministrator struct {
+	ChatMemberBase
+	CanBeEdited bool   `json:"can_be_edited"`
+	CustomTitle string `json:"custom_title,omitempty"`
+	ChatAdministratorRights
+}
+
+type ChatMemberMember struct {
+	ChatMemberBase
+	UntilDate int `json:"until_date,omitempty"`
+}
+
+type ChatMemberRestricted struct {
+	ChatMemberBase
+	IsMember  bool `json:"is_member"`
+	UntilDate int  `json:"until_date"`
+	ChatPermissions
+}
+
+type ChatMemberLeft struct {
+	ChatMemberBase
+}
+
+type ChatMemberBanned struct {
+	ChatMemberBase
+	UntilDate int `json:"until_date"`
+}
+
+// ParseChatMember
+//
+// Chat member of the type selected by the status of the JSON object
+func ParseChatMember(data []byte) (ChatMember, error) {
+	var base ChatMemberBase
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+	switch base.Status {
+	case ChatMemberStatusCreator:
+		return decodeChatMember[ChatMemberOwner](data)
+	case ChatMemberStatusAdministrator:
+		return decodeChatMember[ChatMemberAdministrator](data)
+	case ChatMemberStatusMember:
+		return decodeChatMember[ChatMemberMember](data)
+	case ChatMemberStatusRestricted:
+		return decodeChatMember[ChatMemberRestricted](data)
+	case ChatMemberStatusLeft:
+		return decodeChatMember[ChatMemberLeft](data)
+	case ChatMemberStatusKicked:
+		return decodeChatMember[ChatMemberBanned](data)
+	}
+	return nil, fmt.Errorf("unknown chat member status '%s'", base.Status)
+}
+
+func decodeChatMember[T ChatMember](data []byte) (ChatMember, error) {
+	var m T
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ChatMemberUnion
+//
+// JSON value of a ChatMember, e.g. for Call results
+type ChatMemberUnion struct {
+	ChatMember
+}
+
+func (u *ChatMemberUnion) UnmarshalJSON(data []byte) error {
+	m, err := ParseChatMember(data)
+	if err != nil {
+		return err
+	}
+	u.ChatMember = m
+	return nil
+}
+
+func (u ChatMemberUnion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.ChatMember)
+}
+
+// IsChatAdmin
+//
+// The member is the creator or an administrator of the chat
+func IsChatAdmin(m ChatMember) bool {
+	return m != nil && (m.MemberStatus() == ChatMemberStatusCreator || m.MemberStatus() == ChatMemberStatusAdministrator)
+}
+
+// IsAdmin
+//
+// The user is the creator or an administrator of the chat
+func IsAdmin(ctx context.Context, b Bot, chatId interface{}, userId int) (bool, error) {
+	m, err := GetChatMember{ChatId: chatId, UserId: userId}.Call(ctx, b)
+	if err != nil {
+		return false, err
+	}
+	return IsChatAdmin(m), nil
+}
+
+// AdminCache
+//
+// Administrators of the chats requested with GetChatAdministrators and cached for TTL,
+// so admin commands don't call the API every time. The zero value is ready to use.
+type AdminCache struct {
+	TTL   time.Duration
+	chats map[string]adminCacheEntry
+	now   func() time.Time
+	sync.Mutex
+}
+
+type adminCacheEntry struct {
+	users     map[int]bool
+	expiresAt time.Time
+}
+
+func NewAdminCache(ttl time.Duration) *AdminCache {
+	return &AdminCache{TTL: ttl, chats: make(map[string]adminCacheEntry), now: time.Now}
+}
+
+// IsAdmin
+//
+// The user is the creator or an administrator of the chat
+func (c *AdminCache) IsAdmin(ctx context.Context, b Bot, chatId interface{}, userId int) (bool, error) {
+	key := fmt.Sprint(chatId)
+	c.Lock()
+	entry, ok := c.chats[key]
+	c.Unlock()
+	if ok && c.clock().Before(entry.expiresAt) {
+		return entry.users[userId], nil
+	}
+
+	admins, err := GetChatAdministrators{ChatId: chatId}.Call(ctx, b)
+	if err != nil {
+		return false, err
+	}
+	entry = adminCacheEntry{users: make(map[int]bool), expiresAt: c.clock().Add(c.TTL)}
+	for _, m := range admins {
+		if IsChatAdmin(m) {
+			entry.users[m.MemberUser().Id] = true
+		}
+	}
+	c.Lock()
+	if c.chats == nil {
+		c.chats = make(map[string]adminCacheEntry)
+	}
+	c.chats[key] = entry
+	c.Unlock()
+	return entry.users[userId], nil
+}
+
+// Invalidate
+//
+// Remove the cached administrators of the chat, e.g. after a promotion
+func (c *AdminCache) Invalidate(chatId interface{}) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.chats, fmt.Sprint(chatId))
+}
+
+func (c *AdminCache) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
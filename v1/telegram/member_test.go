@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type callerMock struct {
+	botMock
+	results map[string]string
+	calls   []string
+	err     error
+}
+
+func (cm *callerMock) Call(ctx context.Context, req Request, result interface{}) error {
+	_, method, err := req.GetParams()
+	if err != nil {
+		return err
+	}
+	cm.calls = append(cm.calls, method)
+	if cm.err != nil {
+		return cm.err
+	}
+	return json.Unmarshal([]byte(cm.results[method]), result)
+}
+
+const testAdministrators = `[
+	{"status": "creator", "user": {"id": 1, "is_bot": false, "first_name": "Owner"}, "is_anonymous": false},
+	{"status": "administrator", "user": {"id": 2, "is_bot": false, "first_name": "Admin"},
+		"can_be_edited": true, "custom_title": "Moderator", "can_delete_messages": true, "can_restrict_members": true}
+]`
+
+func TestParseChatMember(t *testing.T) {
+	user := User{Id: 3, FirstName: "User"}
+	tests := []struct {
+		name    string
+		json    string
+		want    ChatMember
+		wantErr bool
+	}{
+		{
+			name: "Owner",
+			json: `{"status": "creator", "user": {"id": 3, "first_name": "User"}, "is_anonymous": true, "custom_title": "Boss"}`,
+			want: ChatMemberOwner{ChatMemberBase: ChatMemberBase{Status: "creator", User: user}, IsAnonymous: true, CustomTitle: "Boss"},
+		},
+		{
+			name: "Administrator",
+			json: `{"status": "administrator", "user": {"id": 3, "first_name": "User"}, "can_be_edited": true, "can_pin_messages": true}`,
+			want: ChatMemberAdministrator{ChatMemberBase: ChatMemberBase{Status: "administrator", User: user}, CanBeEdited: true,
+				ChatAdministratorRights: ChatAdministratorRights{CanPinMessages: true}},
+		},
+		{
+			name: "Member",
+			json: `{"status": "member", "user": {"id": 3, "first_name": "User"}, "until_date": 1700000000}`,
+			want: ChatMemberMember{ChatMemberBase: ChatMemberBase{Status: "member", User: user}, UntilDate: 1700000000},
+		},
+		{
+			name: "Restricted",
+			json: `{"status": "restricted", "user": {"id": 3, "first_name": "User"}, "is_member": true, "can_send_messages": true, "until_date": 0}`,
+			want: ChatMemberRestricted{ChatMemberBase: ChatMemberBase{Status: "restricted", User: user}, IsMember: true,
+				ChatPermissions: ChatPermissions{CanSendMessages: true}},
+		},
+		{
+			name: "Left",
+			json: `{"status": "left", "user": {"id": 3, "first_name": "User"}}`,
+			want: ChatMemberLeft{ChatMemberBase: ChatMemberBase{Status: "left", User: user}},
+		},
+		{
+			name: "Banned",
+			json: `{"status": "kicked", "user": {"id": 3, "first_name": "User"}, "until_date": 1700000000}`,
+			want: ChatMemberBanned{ChatMemberBase: ChatMemberBase{Status: "kicked", User: user}, UntilDate: 1700000000},
+		},
+		{name: "Unknown status", json: `{"status": "unknown"}`, wantErr: true},
+		{name: "Invalid", json: `[]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChatMember([]byte(tt.json))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseChatMember() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ParseChatMember() difference: %v", diff)
+			}
+			if tt.wantErr {
+				return
+			}
+			data, err := json.Marshal(ChatMemberUnion{got})
+			if err != nil {
+				t.Fatalf("ChatMemberUnion.MarshalJSON() error = %v", err)
+			}
+			var u ChatMemberUnion
+			if err := json.Unmarshal(data, &u); err != nil {
+				t.Fatalf("ChatMemberUnion.UnmarshalJSON() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, u.ChatMember); diff != "" {
+				t.Errorf("ChatMemberUnion round trip difference: %v", diff)
+			}
+		})
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		err     error
+		want    bool
+		wantErr bool
+	}{
+		{name: "Creator", result: `{"status": "creator", "user": {"id": 1}}`, want: true},
+		{name: "Administrator", result: `{"status": "administrator", "user": {"id": 1}}`, want: true},
+		{name: "Member", result: `{"status": "member", "user": {"id": 1}}`, want: false},
+		{name: "Error", err: errors.New("call error"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := &callerMock{results: map[string]string{"getChatMember": tt.result}, err: tt.err}
+			got, err := IsAdmin(context.Background(), cm, -100, 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IsAdmin() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("IsAdmin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminCache_IsAdmin(t *testing.T) {
+	cm := &callerMock{results: map[string]string{"getChatAdministrators": testAdministrators}}
+	now := time.Now()
+	cache := NewAdminCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		userId int
+		want   bool
+	}{{1, true}, {2, true}, {3, false}} {
+		got, err := cache.IsAdmin(ctx, cm, -100, tt.userId)
+		if err != nil || got != tt.want {
+			t.Errorf("AdminCache.IsAdmin(%d) = %v, %v, want %v", tt.userId, got, err, tt.want)
+		}
+	}
+	if len(cm.calls) != 1 {
+		t.Errorf("AdminCache.IsAdmin() calls = %v, want one", cm.calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := cache.IsAdmin(ctx, cm, -100, 1); err != nil || len(cm.calls) != 2 {
+		t.Errorf("AdminCache.IsAdmin() expired calls = %v, error = %v", cm.calls, err)
+	}
+	cache.Invalidate(-100)
+	if _, err := cache.IsAdmin(ctx, cm, -100, 1); err != nil || len(cm.calls) != 3 {
+		t.Errorf("AdminCache.IsAdmin() invalidated calls = %v, error = %v", cm.calls, err)
+	}
+
+	cm.err = errors.New("call error")
+	if _, err := cache.IsAdmin(ctx, cm, -200, 1); !errors.Is(err, cm.err) {
+		t.Errorf("AdminCache.IsAdmin() error = %v, wantErr %v", err, cm.err)
+	}
+}
+
+func TestAdminCache_ZeroValue(t *testing.T) {
+	cm := &callerMock{results: map[string]string{"getChatAdministrators": testAdministrators}}
+	cache := AdminCache{TTL: time.Minute}
+	cache.Invalidate(-100)
+	for i := 0; i < 2; i++ {
+		if got, err := cache.IsAdmin(context.Background(), cm, -100, 1); err != nil || !got {
+			t.Errorf("AdminCache.IsAdmin() = %v, error = %v, want %v", got, err, true)
+		}
+	}
+	if len(cm.calls) != 1 {
+		t.Errorf("AdminCache.IsAdmin() calls = %v, want one", cm.calls)
+	}
+}
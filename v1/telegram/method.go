@@ -37,6 +37,97 @@ func (req AnswerCallbackQuery) GetParams() (val url.Values, method string, err e
 	return
 }
 
+// CopyMessage
+//
+// Copy the message without a link to the original one, Caption replaces the caption of a media message
+type CopyMessage struct {
+	ChatId                   interface{}     `json:"chat_id"`
+	MessageThreadId          int             `json:"message_thread_id,omitempty"`
+	FromChatId               interface{}     `json:"from_chat_id"`
+	MessageId                int             `json:"message_id"`
+	Caption                  *string         `json:"caption,omitempty"`
+	ParseMode                string          `json:"parse_mode,omitempty"`
+	CaptionEntities          []MessageEntity `json:"caption_entities,omitempty"`
+	DisableNotification      bool            `json:"disable_notification,omitempty"`
+	ProtectContent           bool            `json:"protect_content,omitempty"`
+	ReplyToMessageId         int             `json:"reply_to_message_id,omitempty"`
+	AllowSendingWithoutReply bool            `json:"allow_sending_without_reply,omitempty"`
+	ReplyMarkup              interface{}     `json:"reply_markup,omitempty"`
+}
+
+func (req CopyMessage) GetParams() (val url.Values, method string, err error) {
+	method = "copyMessage"
+	if req.ChatId == nil || req.FromChatId == nil || req.MessageId == 0 {
+		return nil, "",
+			fmt.Errorf("required fields not defined, ChatId: %v, FromChatId: %v, MessageId: %d", req.ChatId, req.FromChatId, req.MessageId)
+	}
+	val = url.Values{}
+	val.Add("chat_id", fmt.Sprint(req.ChatId))
+	val.Add("from_chat_id", fmt.Sprint(req.FromChatId))
+	val.Add("message_id", strconv.Itoa(req.MessageId))
+	if req.MessageThreadId != 0 {
+		val.Add("message_thread_id", strconv.Itoa(req.MessageThreadId))
+	}
+	if req.Caption != nil {
+		val.Add("caption", *req.Caption)
+	}
+	if req.ParseMode != "" {
+		val.Add("parse_mode", req.ParseMode)
+	}
+	if len(req.CaptionEntities) > 0 {
+		if err := addJSON(val, "caption_entities", req.CaptionEntities); err != nil {
+			return nil, "", err
+		}
+	}
+	if req.DisableNotification {
+		val.Add("disable_notification", strconv.FormatBool(req.DisableNotification))
+	}
+	if req.ProtectContent {
+		val.Add("protect_content", strconv.FormatBool(req.ProtectContent))
+	}
+	if req.ReplyToMessageId > 0 {
+		val.Add("reply_to_message_id", strconv.Itoa(req.ReplyToMessageId))
+	}
+	if req.AllowSendingWithoutReply {
+		val.Add("allow_sending_without_reply", strconv.FormatBool(req.AllowSendingWithoutReply))
+	}
+	if req.ReplyMarkup != nil {
+		if err := addJSON(val, "reply_markup", req.ReplyMarkup); err != nil {
+			return nil, "", err
+		}
+	}
+	return
+}
+
+// CopyMessages
+//
+// Copy the messages of the chat, the result is []MessageId of the sent messages
+type CopyMessages struct {
+	ChatId              interface{} `json:"chat_id"`
+	MessageThreadId     int         `json:"message_thread_id,omitempty"`
+	FromChatId          interface{} `json:"from_chat_id"`
+	MessageIds          []int       `json:"message_ids"`
+	DisableNotification bool        `json:"disable_notification,omitempty"`
+	ProtectContent      bool        `json:"protect_content,omitempty"`
+	RemoveCaption       bool        `json:"remove_caption,omitempty"`
+}
+
+func (req CopyMessages) GetParams() (val url.Values, method string, err error) {
+	method = "copyMessages"
+	if len(req.MessageIds) == 0 {
+		return nil, "", fmt.Errorf("required fields not defined, MessageIds: %v", req.MessageIds)
+	}
+	val, err = messagesParams(req.ChatId, req.MessageThreadId, req.FromChatId, req.MessageIds,
+		req.DisableNotification, req.ProtectContent)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.RemoveCaption {
+		val.Add("remove_caption", strconv.FormatBool(req.RemoveCaption))
+	}
+	return
+}
+
 type DeleteMessage struct {
 	ChatId    interface{} `json:"chat_id"`
 	MessageId int         `json:"message_id"`
@@ -146,6 +237,85 @@ func (req DeleteMessage) GetParams() (url.Values, string, error) {
 	return val, method, nil
 }
 
+// ForwardMessage
+//
+// Forward the message of the chat
+type ForwardMessage struct {
+	ChatId              interface{} `json:"chat_id"`
+	MessageThreadId     int         `json:"message_thread_id,omitempty"`
+	FromChatId          interface{} `json:"from_chat_id"`
+	MessageId           int         `json:"message_id"`
+	DisableNotification bool        `json:"disable_notification,omitempty"`
+	ProtectContent      bool        `json:"protect_content,omitempty"`
+}
+
+func (req ForwardMessage) GetParams() (val url.Values, method string, err error) {
+	method = "forwardMessage"
+	if req.MessageId == 0 {
+		return nil, "", fmt.Errorf("required fields not defined, MessageId: %d", req.MessageId)
+	}
+	val, err = messagesParams(req.ChatId, req.MessageThreadId, req.FromChatId, nil,
+		req.DisableNotification, req.ProtectContent)
+	if err != nil {
+		return nil, "", err
+	}
+	val.Add("message_id", strconv.Itoa(req.MessageId))
+	return
+}
+
+// ForwardMessages
+//
+// Forward the messages of the chat, the result is []MessageId of the sent messages
+type ForwardMessages struct {
+	ChatId              interface{} `json:"chat_id"`
+	MessageThreadId     int         `json:"message_thread_id,omitempty"`
+	FromChatId          interface{} `json:"from_chat_id"`
+	MessageIds          []int       `json:"message_ids"`
+	DisableNotification bool        `json:"disable_notification,omitempty"`
+	ProtectContent      bool        `json:"protect_content,omitempty"`
+}
+
+func (req ForwardMessages) GetParams() (val url.Values, method string, err error) {
+	method = "forwardMessages"
+	if len(req.MessageIds) == 0 {
+		return nil, "", fmt.Errorf("required fields not defined, MessageIds: %v", req.MessageIds)
+	}
+	val, err = messagesParams(req.ChatId, req.MessageThreadId, req.FromChatId, req.MessageIds,
+		req.DisableNotification, req.ProtectContent)
+	if err != nil {
+		return nil, "", err
+	}
+	return
+}
+
+// messagesParams
+//
+// Common params of the forward and copy requests
+func messagesParams(chatId interface{}, threadId int, fromChatId interface{}, messageIds []int,
+	disableNotification bool, protectContent bool) (url.Values, error) {
+	if chatId == nil || fromChatId == nil {
+		return nil, fmt.Errorf("required fields not defined, ChatId: %v, FromChatId: %v", chatId, fromChatId)
+	}
+	val := url.Values{}
+	val.Add("chat_id", fmt.Sprint(chatId))
+	val.Add("from_chat_id", fmt.Sprint(fromChatId))
+	if len(messageIds) > 0 {
+		if err := addJSON(val, "message_ids", messageIds); err != nil {
+			return nil, err
+		}
+	}
+	if threadId != 0 {
+		val.Add("message_thread_id", strconv.Itoa(threadId))
+	}
+	if disableNotification {
+		val.Add("disable_notification", strconv.FormatBool(disableNotification))
+	}
+	if protectContent {
+		val.Add("protect_content", strconv.FormatBool(protectContent))
+	}
+	return val, nil
+}
+
 type SendInvoice struct {
 	ChatId        interface{}          `json:"chat_id"`
 	Title         string               `json:"title"`
@@ -279,3 +449,15 @@ type LabeledPrice struct {
 	Label  string `json:"label"`
 	Amount int    `json:"amount"`
 }
+
+// addJSON
+//
+// Add the JSON serialized value of the param
+func addJSON(val url.Values, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	val.Add(key, string(data))
+	return nil
+}
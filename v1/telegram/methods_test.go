@@ -503,3 +503,80 @@ func TestAnswerCallbackQuery_GetParams(t *testing.T) {
 		})
 	}
 }
+
+func TestForwardCopy_GetParams(t *testing.T) {
+	caption := ""
+	tests := []struct {
+		name       string
+		request    Request
+		wantVal    url.Values
+		wantMethod string
+		wantErr    bool
+	}{
+		{
+			name:       "ForwardMessage",
+			request:    ForwardMessage{ChatId: -100, FromChatId: 1, MessageId: 10},
+			wantVal:    url.Values{"chat_id": {"-100"}, "from_chat_id": {"1"}, "message_id": {"10"}},
+			wantMethod: "forwardMessage",
+		},
+		{
+			name: "ForwardMessage full fields",
+			request: ForwardMessage{ChatId: -100, MessageThreadId: 3, FromChatId: "@channel", MessageId: 10,
+				DisableNotification: true, ProtectContent: true},
+			wantVal: url.Values{"chat_id": {"-100"}, "message_thread_id": {"3"}, "from_chat_id": {"@channel"},
+				"message_id": {"10"}, "disable_notification": {"true"}, "protect_content": {"true"}},
+			wantMethod: "forwardMessage",
+		},
+		{name: "ForwardMessage without message", request: ForwardMessage{ChatId: -100, FromChatId: 1}, wantErr: true},
+		{name: "ForwardMessage without chat", request: ForwardMessage{FromChatId: 1, MessageId: 10}, wantErr: true},
+		{
+			name:       "ForwardMessages",
+			request:    ForwardMessages{ChatId: -100, FromChatId: 1, MessageIds: []int{10, 11}, ProtectContent: true},
+			wantVal:    url.Values{"chat_id": {"-100"}, "from_chat_id": {"1"}, "message_ids": {"[10,11]"}, "protect_content": {"true"}},
+			wantMethod: "forwardMessages",
+		},
+		{name: "ForwardMessages without messages", request: ForwardMessages{ChatId: -100, FromChatId: 1}, wantErr: true},
+		{
+			name:       "CopyMessage",
+			request:    CopyMessage{ChatId: -100, FromChatId: 1, MessageId: 10},
+			wantVal:    url.Values{"chat_id": {"-100"}, "from_chat_id": {"1"}, "message_id": {"10"}},
+			wantMethod: "copyMessage",
+		},
+		{
+			name: "CopyMessage full fields",
+			request: CopyMessage{ChatId: -100, MessageThreadId: 3, FromChatId: 1, MessageId: 10, Caption: &caption,
+				ParseMode: "HTML", CaptionEntities: []MessageEntity{{Type: "bold", Offset: 0, Length: 1}},
+				DisableNotification: true, ProtectContent: true, ReplyToMessageId: 5, AllowSendingWithoutReply: true,
+				ReplyMarkup: InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{{{Text: "Ok", CallbackData: "ok"}}}}},
+			wantVal: url.Values{"chat_id": {"-100"}, "message_thread_id": {"3"}, "from_chat_id": {"1"}, "message_id": {"10"},
+				"caption": {""}, "parse_mode": {"HTML"}, "caption_entities": {`[{"type":"bold","offset":0,"length":1}]`},
+				"disable_notification": {"true"}, "protect_content": {"true"}, "reply_to_message_id": {"5"},
+				"allow_sending_without_reply": {"true"},
+				"reply_markup":                {`{"inline_keyboard":[[{"text":"Ok","callback_data":"ok"}]]}`}},
+			wantMethod: "copyMessage",
+		},
+		{name: "CopyMessage without from chat", request: CopyMessage{ChatId: -100, MessageId: 10}, wantErr: true},
+		{
+			name:       "CopyMessages",
+			request:    CopyMessages{ChatId: -100, FromChatId: 1, MessageIds: []int{10}, RemoveCaption: true},
+			wantVal:    url.Values{"chat_id": {"-100"}, "from_chat_id": {"1"}, "message_ids": {"[10]"}, "remove_caption": {"true"}},
+			wantMethod: "copyMessages",
+		},
+		{name: "CopyMessages without messages", request: CopyMessages{ChatId: -100, FromChatId: 1, MessageIds: []int{}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotVal, gotMethod, err := tt.request.GetParams()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%T.GetParams() error = %v, wantErr %v", tt.request, err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.wantVal, gotVal); diff != "" {
+				t.Errorf("%T.GetParams() difference %v", tt.request, diff)
+			}
+			if gotMethod != tt.wantMethod {
+				t.Errorf("%T.GetParams() gotMethod = %v, want %v", tt.request, gotMethod, tt.wantMethod)
+			}
+		})
+	}
+}
@@ -15,10 +15,11 @@ type UpdateResponse struct {
 }
 
 type MessageResponse struct {
-	Ok         bool               `json:"ok"`
-	Result     Message            `json:"result"`
-	ErrorCode  int                `json:"error_code"`
-	Parameters ResponseParameters `json:"parameters"`
+	Ok          bool               `json:"ok"`
+	Result      Message            `json:"result"`
+	Description string             `json:"description"`
+	ErrorCode   int                `json:"error_code"`
+	Parameters  ResponseParameters `json:"parameters"`
 }
 
 type ResponseParameters struct {
@@ -54,22 +55,42 @@ func (ur *UpdateResponse) Parse(reader io.Reader) error {
 			*ur = UpdateResponse{}
 			return err
 		}
+		if ur.Result[i].CallbackQuery.Message, err = normalizeMessage(update.CallbackQuery.Message); err != nil {
+			*ur = UpdateResponse{}
+			return err
+		}
+		if ur.Result[i].ChatJoinRequest.Chat, err = normalizeChat(update.ChatJoinRequest.Chat); err != nil {
+			*ur = UpdateResponse{}
+			return err
+		}
 	}
 	return nil
 }
 
+// normalizeChat
+//
+// Chat with the JSON number id converted to int
+func normalizeChat(c Chat) (Chat, error) {
+	switch val := c.Id.(type) {
+	case nil:
+	case float64:
+		c.Id = int(val)
+	case int:
+	case string:
+	default:
+		return Chat{}, fmt.Errorf("invalid chat Id type %v", val)
+	}
+	return c, nil
+}
+
 func normalizeMessage(m Message) (Message, error) {
 	if m.Chat.Id == nil {
 		return m, nil
 	}
 
-	switch val := m.Chat.Id.(type) {
-	case float64:
-		m.Chat.Id = int(val)
-	case int:
-	case string:
-	default:
-		return Message{}, fmt.Errorf("invalid chat Id type %v", val)
+	var err error
+	if m.Chat, err = normalizeChat(m.Chat); err != nil {
+		return Message{}, err
 	}
 
 	if m.ReplyToMessage != nil {
@@ -86,12 +107,22 @@ func normalizeMessage(m Message) (Message, error) {
 }
 
 func (mr *MessageResponse) Parse(reader io.Reader) error {
-	err := ParseJson(mr, reader)
-	if err != nil {
+	resp := struct {
+		MessageResponse
+		Result json.RawMessage `json:"result"`
+	}{}
+	if err := ParseJson(&resp, reader); err != nil {
 		return err
 	}
-	mr.Result, err = normalizeMessage(mr.Result)
-
+	*mr = resp.MessageResponse
+	// Results of the requests like banChatMember are true instead of a message
+	if len(resp.Result) == 0 || resp.Result[0] != '{' {
+		return nil
+	}
+	err := json.Unmarshal(resp.Result, &mr.Result)
+	if err == nil {
+		mr.Result, err = normalizeMessage(mr.Result)
+	}
 	if err != nil {
 		*mr = MessageResponse{}
 		return err
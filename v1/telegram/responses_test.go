@@ -102,6 +102,11 @@ func TestMessageResponse_Parse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Boolean result",
+			json: `{"ok": true, "result": true}`,
+			want: MessageResponse{Ok: true},
+		},
 		{
 			name: "Wrong chat id",
 			json: `{
@@ -192,6 +197,77 @@ func TestUpdateResponse_Parse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Chat join request",
+			json: `{
+				"ok": true,
+				"result": [
+					{
+						"update_id": 123130162,
+						"chat_join_request": {
+							"chat": {"id": -1001234567890, "title": "Group", "type": "supergroup"},
+							"from": {"id": 10, "is_bot": false, "first_name": "Alexey"},
+							"user_chat_id": 10,
+							"date": 1630134810,
+							"bio": "Gopher",
+							"invite_link": {"invite_link": "https://t.me/+AbCd...", "creator": {"id": 1, "is_bot": true, "first_name": "Bot"},
+								"creates_join_request": true, "is_primary": false, "is_revoked": false, "name": "Questionnaire"}
+						}
+					}
+				]
+			}`,
+			want: UpdateResponse{
+				Ok: true,
+				Result: []Update{
+					{
+						UpdateId: 123130162,
+						ChatJoinRequest: ChatJoinRequest{
+							Chat:       Chat{Id: -1001234567890, Title: "Group", Type: "supergroup"},
+							From:       User{Id: 10, FirstName: "Alexey"},
+							UserChatId: 10,
+							Date:       1630134810,
+							Bio:        "Gopher",
+							InviteLink: &ChatInviteLink{InviteLink: "https://t.me/+AbCd...", Creator: User{Id: 1, IsBot: true, FirstName: "Bot"},
+								CreatesJoinRequest: true, Name: "Questionnaire"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Callback query",
+			json: `{"ok": true, "result": [{"update_id": 123130163, "callback_query": {"id": "7", "from": {"id": 10},
+				"message": {"message_id": 2468, "chat": {"id": 123456789, "type": "private"}}, "data": "menu_main_open"}}]}`,
+			want: UpdateResponse{
+				Ok: true,
+				Result: []Update{
+					{
+						UpdateId: 123130163,
+						CallbackQuery: CallbackQuery{Id: "7", From: User{Id: 10}, Data: "menu_main_open",
+							Message: Message{MessageId: 2468, Chat: Chat{Id: 123456789, Type: "private"}}},
+					},
+				},
+			},
+		},
+		{
+			name: "Wrong callback message chat id",
+			json: `{"ok": true, "result": [{"update_id": 123130163, "callback_query": {"id": "7",
+				"message": {"message_id": 2468, "chat": {"id": true, "type": "private"}}}}]}`,
+			wantErr: true,
+		},
+		{
+			name: "Wrong chat join request chat id",
+			json: `{
+				"ok": true,
+				"result": [
+					{
+						"update_id": 123130162,
+						"chat_join_request": {"chat": {"id": true, "type": "supergroup"}, "from": {"id": 10}, "date": 1630134810}
+					}
+				]
+			}`,
+			wantErr: true,
+		},
 		{
 			name: "Wrong message chat id",
 			json: `{